@@ -1,23 +1,194 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"myproject/internal/config"
 	"myproject/internal/handler"
+	"myproject/internal/metrics"
+	"myproject/internal/middleware"
 	"myproject/internal/repository"
 	"myproject/internal/service"
+	"myproject/internal/utils"
+
+	_ "github.com/lib/pq" // database.driver "postgres"
 )
 
 func main() {
-	var db *sql.DB
-	// db, err := sql.Open("driver", "dsn") — real DB when needed
+	if len(os.Args) > 1 && os.Args[1] == "token" {
+		runToken(os.Args[2:])
+		return
+	}
+	configPath := flag.String("config", "configs/config.yaml", "config file (defaults are used when it does not exist)")
+	flag.Parse()
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger := newLogger(cfg.Logging)
 
+	db, err := openDB(cfg.Database, logger)
+	if err != nil {
+		logger.Error("users DB unavailable", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 	repo := repository.NewUserRepo(db)
 	svc := service.NewUserService(repo)
 	h := handler.NewUserHandler(svc)
 
-	http.HandleFunc("/users", h.List)
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	// Records DB, remote servers and utils for sync runs, as in sync-cli. The
+	// /sync routes are only served once localDB and ut are set.
+	var localDB repository.DB
+	getRemoteDB := func(serverID int) repository.DB {
+		return nil
+	}
+	var ut utils.Utils
+	audit := service.NewAuditService(repository.NewAuditRepo(db))
+	newSyncService := func() *service.SyncService {
+		svc := service.NewSyncService(localDB, getRemoteDB, ut)
+		svc.Paths = service.PathLayoutFromEnv(svc.Paths)
+		svc.Audit = audit
+		return svc
+	}
+	runner := service.NewSyncRunner(newSyncService)
+	sh := handler.NewSyncHandler(runner)
+
+	auth := handler.NewAuthenticator(service.NewAuthService(repository.NewTokenRepo(db)), audit)
+	router := handler.NewRouter(auth, handler.NewAuditHandler(audit))
+	router.Users(h)
+	health := service.NewHealthService(nil)
+	if localDB != nil && ut != nil {
+		router.SyncRuns(sh)
+		health = service.NewHealthService(newSyncService)
+	} else {
+		logger.Warn("no records DB or utils configured, /sync routes and their readiness checks disabled")
+	}
+	router.Audit()
+	router.Health(handler.NewHealthHandler(svc, health))
+	router.Handle("GET /metrics", "", metrics.Handler().ServeHTTP)
+
+	srv := &http.Server{
+		Addr: cfg.Server.Addr(),
+		Handler: middleware.Chain(router,
+			middleware.RequestID,
+			middleware.AccessLog(logger),
+			middleware.Recover(logger),
+			middleware.Timeout(cfg.Server.RequestTimeout(), router.IsStream),
+		),
+		ReadHeaderTimeout: cfg.Server.ReadTimeout(),
+		ReadTimeout:       cfg.Server.ReadTimeout(),
+		WriteTimeout:      cfg.Server.WriteTimeout(),
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("listening", slog.String("addr", srv.Addr))
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		logger.Error("server failed", slog.String("error", err.Error()))
+		closeDB(repo, db, logger)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Drain: running sync runs stop after their current item (which also ends
+	// their event streams), then in-flight requests finish, then the DB closes.
+	logger.Info("shutting down", slog.Duration("timeout", cfg.Server.ShutdownTimeout()))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout())
+	defer cancel()
+	failed := false
+	if err := runner.Shutdown(shutdownCtx); err != nil {
+		logger.Error("stopping sync runs", slog.String("error", err.Error()))
+		failed = true
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("draining requests", slog.String("error", err.Error()))
+		failed = true
+	}
+	closeDB(repo, db, logger)
+	if failed {
+		os.Exit(1)
+	}
+	logger.Info("stopped")
+}
+
+// loadConfig reads the config file; a missing file means the defaults.
+func loadConfig(path string) (config.Config, error) {
+	cfg, err := config.Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Printf("config %s not found, using defaults", path)
+		return config.Default(), nil
+	}
+	return cfg, err
+}
+
+func newLogger(c config.Logging) *slog.Logger {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(c.Level))
+	opts := &slog.HandlerOptions{Level: level}
+	if c.Format == "text" {
+		return slog.New(slog.NewTextHandler(os.Stdout, opts))
+	}
+	return slog.New(slog.NewJSONHandler(os.Stdout, opts))
+}
+
+// dbPingTimeout bounds the first connection to the users DB at startup.
+const dbPingTimeout = 10 * time.Second
+
+// openDB opens the users DB pool and checks that it answers. Without a DSN the
+// API runs without a DB (empty lists, errors on writes); a configured DB that
+// can't be opened or reached is an error.
+func openDB(c config.Database, logger *slog.Logger) (*sql.DB, error) {
+	if c.DSN == "" {
+		logger.Warn("no database.dsn configured, running without users DB")
+		return nil, nil
+	}
+	db, err := sql.Open(c.Driver, c.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("open %s DB: %w", c.Driver, err)
+	}
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime())
+	ctx, cancel := context.WithTimeout(context.Background(), dbPingTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping %s DB: %w", c.Driver, err)
+	}
+	return db, nil
+}
+
+// closeDB releases the prepared statements and then the pool.
+func closeDB(repo *repository.UserRepo, db *sql.DB, logger *slog.Logger) {
+	if err := repo.Close(); err != nil {
+		logger.Error("closing statements", slog.String("error", err.Error()))
+	}
+	if db == nil {
+		return
+	}
+	if err := db.Close(); err != nil {
+		logger.Error("closing DB", slog.String("error", err.Error()))
+	}
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"myproject/internal/model"
+	"myproject/internal/repository"
+	"myproject/internal/service"
+)
+
+func printTokenHelp() {
+	fmt.Println(`Usage:
+  myapp token issue  -name NAME -role viewer|operator|admin [-config FILE]
+  myapp token revoke -id N [-config FILE]
+  myapp token list   [-config FILE]`)
+}
+
+// runToken manages API tokens in the users DB of the config file.
+func runToken(args []string) {
+	if len(args) == 0 {
+		printTokenHelp()
+		os.Exit(1)
+	}
+	fs := flag.NewFlagSet("token "+args[0], flag.ExitOnError)
+	configPath := fs.String("config", "configs/config.yaml", "config file")
+	var name, role *string
+	var id *int64
+	switch args[0] {
+	case "issue":
+		name = fs.String("name", "", "who or what uses the token, e.g. ci-sync")
+		role = fs.String("role", model.RoleViewer, "viewer, operator or admin")
+	case "revoke":
+		id = fs.Int64("id", 0, "token ID (see token list)")
+	case "list":
+	default:
+		fmt.Println("Unknown token command:", args[0])
+		printTokenHelp()
+		os.Exit(1)
+	}
+	_ = fs.Parse(args[1:])
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	db, err := openDB(cfg.Database, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if db != nil {
+		defer db.Close()
+	}
+	svc := service.NewAuthService(repository.NewTokenRepo(db))
+
+	switch args[0] {
+	case "issue":
+		token, t, err := svc.Issue(*name, *role)
+		if err != nil {
+			fmt.Println("issue token:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("token %d issued for %s (%s)\n", t.ID, t.Name, t.Role)
+		fmt.Println(token)
+		fmt.Println("Store it now, it cannot be shown again.")
+	case "revoke":
+		if err := svc.Revoke(*id); err != nil {
+			fmt.Println("revoke token:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("token %d revoked\n", *id)
+	case "list":
+		tokens, err := svc.List()
+		if err != nil {
+			fmt.Println("list tokens:", err)
+			os.Exit(1)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tROLE\tCREATED\tREVOKED")
+		for _, t := range tokens {
+			revoked := "-"
+			if t.RevokedAt != nil {
+				revoked = t.RevokedAt.Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Role, t.CreatedAt.Format("2006-01-02 15:04"), revoked)
+		}
+		tw.Flush()
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"myproject/internal/service"
+)
+
+// runCompare implements `sync-cli compare -start -end -stream_type [-stream_id] [-format]`.
+func runCompare(svc *service.SyncService, args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	startStr := fs.String("start", "", `start datetime "YYYY-MM-DD HH:mm"`)
+	endStr := fs.String("end", "", `end datetime "YYYY-MM-DD HH:mm"`)
+	streamType := fs.String("stream_type", "", "stream type - `audio` or `video`")
+	streamID := fs.Int("stream_id", -1, "only stream with id")
+	format := fs.String("format", "table", "output format - `table`, `csv` or `json`")
+	_ = fs.Parse(args)
+
+	st, et := parseWindow(*startStr, *endStr)
+	stype := requireStreamType(*streamType)
+	checkFormat(*format)
+
+	comparison, err := svc.Compare(st, et, stype, *streamID)
+	if err != nil {
+		fmt.Println("Error comparing coverage:", err)
+		os.Exit(1)
+	}
+	ff := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(comparison)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		_ = w.Write([]string{"stream_id", "stream_name", "server", "in_import_order", "recorded_minutes",
+			"coverage_percent", "fill_minutes", "unique_minutes", "periods", "error"})
+		for _, c := range comparison {
+			id := strconv.Itoa(c.StreamID)
+			_ = w.Write([]string{id, c.StreamName, "local", "", ff(c.Local.RecordedMinutes),
+				ff(c.Local.CoveragePercent), "", ff(c.LocalUniqueMinutes), formatPeriods(c.Local.Gaps), ""})
+			for _, sc := range c.Servers {
+				_ = w.Write([]string{id, c.StreamName, strconv.Itoa(sc.ServerID), strconv.FormatBool(sc.InImportOrder),
+					ff(sc.RecordedMinutes), ff(sc.CoveragePercent), ff(sc.FillMinutes), ff(sc.UniqueMinutes),
+					formatPeriods(sc.Fills), sc.Error})
+			}
+			_ = w.Write([]string{id, c.StreamName, "unfilled", "", "", "", ff(c.UnfilledMinutes), "",
+				formatPeriods(c.Unfilled), ""})
+		}
+		w.Flush()
+	default:
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, c := range comparison {
+			fmt.Fprintf(tw, "stream %d %s: local %.2f%%, gaps %.2f min, unfilled %.2f min\n", c.StreamID, c.StreamName,
+				c.Local.CoveragePercent, c.Local.GapMinutes, c.UnfilledMinutes)
+			fmt.Fprintln(tw, "  SERVER\tORDER\tCOVERAGE %\tFILLS MIN\tUNIQUE MIN\tFILLS")
+			fmt.Fprintf(tw, "  local\t-\t%.2f\t-\t%.2f\t\n", c.Local.CoveragePercent, c.LocalUniqueMinutes)
+			for i, sc := range c.Servers {
+				order := "-"
+				if sc.InImportOrder {
+					order = strconv.Itoa(i + 1)
+				}
+				if sc.Error != "" {
+					fmt.Fprintf(tw, "  %d\t%s\terror: %s\t\t\t\n", sc.ServerID, order, sc.Error)
+					continue
+				}
+				fmt.Fprintf(tw, "  %d\t%s\t%.2f\t%.2f\t%.2f\t%d\n", sc.ServerID, order, sc.CoveragePercent,
+					sc.FillMinutes, sc.UniqueMinutes, len(sc.Fills))
+				for _, p := range sc.Fills {
+					fmt.Fprintf(tw, "\t\t\t\t\t%s - %s (%.1f min)\n", p.Start.Format(periodLayout), p.End.Format(periodLayout),
+						p.End.Sub(p.Start).Minutes())
+				}
+			}
+			tw.Flush()
+			fmt.Println()
+		}
+	}
+}
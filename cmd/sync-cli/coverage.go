@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"myproject/internal/model"
+	"myproject/internal/service"
+)
+
+const periodLayout = "2006-01-02 15:04"
+
+// parseWindow parses the -start / -end flags shared by the report subcommands.
+func parseWindow(startStr, endStr string) (time.Time, time.Time) {
+	if startStr == "" || endStr == "" {
+		fmt.Println("start and end are required")
+		printHelp()
+		os.Exit(1)
+	}
+	st, err := validDateTimeType(startStr)
+	if err != nil {
+		fmt.Println("Given Datetime not valid! Expected format, 'YYYY-MM-DD HH:mm'!")
+		os.Exit(1)
+	}
+	et, err := validDateTimeType(endStr)
+	if err != nil {
+		fmt.Println("Given Datetime not valid! Expected format, 'YYYY-MM-DD HH:mm'!")
+		os.Exit(1)
+	}
+	if !st.Before(et) {
+		fmt.Println("start must be before end")
+		os.Exit(1)
+	}
+	return st, et
+}
+
+// requireStreamType validates the -stream_type flag shared by the subcommands.
+func requireStreamType(value string) string {
+	if value == "" {
+		fmt.Println("stream_type is required")
+		os.Exit(1)
+	}
+	stype, err := checkStreamType(value)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return stype
+}
+
+func checkFormat(format string) {
+	switch format {
+	case "table", "csv", "json":
+	default:
+		fmt.Println("format - `table`, `csv` or `json`")
+		os.Exit(1)
+	}
+}
+
+func formatPeriods(periods []model.Period) string {
+	parts := make([]string, len(periods))
+	for i, p := range periods {
+		parts[i] = p.Start.Format(periodLayout) + "/" + p.End.Format(periodLayout)
+	}
+	return strings.Join(parts, ";")
+}
+
+// runCoverage implements `sync-cli coverage -start -end -stream_type [-stream_id] [-format]`.
+func runCoverage(svc *service.SyncService, args []string) {
+	fs := flag.NewFlagSet("coverage", flag.ExitOnError)
+	startStr := fs.String("start", "", `start datetime "YYYY-MM-DD HH:mm"`)
+	endStr := fs.String("end", "", `end datetime "YYYY-MM-DD HH:mm"`)
+	streamType := fs.String("stream_type", "", "stream type - `audio` or `video`")
+	streamID := fs.Int("stream_id", -1, "only stream with id")
+	format := fs.String("format", "table", "output format - `table`, `csv` or `json`")
+	_ = fs.Parse(args)
+
+	st, et := parseWindow(*startStr, *endStr)
+	stype := requireStreamType(*streamType)
+	checkFormat(*format)
+
+	coverage, err := svc.Coverage(svc.LocalDB, st, et, stype, *streamID)
+	if err != nil {
+		fmt.Println("Error computing coverage:", err)
+		os.Exit(1)
+	}
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(coverage)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		_ = w.Write([]string{"stream_id", "stream_name", "recorded_minutes", "gap_minutes", "coverage_percent", "gaps"})
+		for _, c := range coverage {
+			_ = w.Write([]string{
+				strconv.Itoa(c.StreamID), c.StreamName,
+				strconv.FormatFloat(c.RecordedMinutes, 'f', 2, 64),
+				strconv.FormatFloat(c.GapMinutes, 'f', 2, 64),
+				strconv.FormatFloat(c.CoveragePercent, 'f', 2, 64),
+				formatPeriods(c.Gaps),
+			})
+		}
+		w.Flush()
+	default:
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "STREAM\tNAME\tRECORDED MIN\tGAP MIN\tCOVERAGE %\tGAPS")
+		for _, c := range coverage {
+			fmt.Fprintf(tw, "%d\t%s\t%.2f\t%.2f\t%.2f\t%d\n", c.StreamID, c.StreamName,
+				c.RecordedMinutes, c.GapMinutes, c.CoveragePercent, len(c.Gaps))
+			for _, g := range c.Gaps {
+				fmt.Fprintf(tw, "\t\t\t\t\t%s - %s (%.1f min)\n", g.Start.Format(periodLayout), g.End.Format(periodLayout),
+					g.End.Sub(g.Start).Minutes())
+			}
+		}
+		tw.Flush()
+	}
+}
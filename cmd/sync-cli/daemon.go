@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"myproject/internal/metrics"
+	"myproject/internal/scheduler"
+	"myproject/internal/service"
+)
+
+// daemonJob is one entry of the daemon jobs file. Omitted overlap_min and
+// min_free_gb take the defaults of the auto flags.
+type daemonJob struct {
+	Name        string   `json:"name"`
+	Schedule    string   `json:"schedule"`
+	StreamType  string   `json:"stream_type"`
+	Days        int      `json:"days"`
+	Hours       int      `json:"hours"`
+	Sync        bool     `json:"sync"`
+	AddMode     bool     `json:"add_mode"`
+	NoTask      bool     `json:"no_task"`
+	Incremental bool     `json:"incremental"`
+	OverlapMin  *int     `json:"overlap_min"`
+	MinFreeGB   *float64 `json:"min_free_gb"`
+}
+
+// daemonSyncGroup is the scheduler group of every sync job: a sync run takes
+// the global records_sync task, so jobs of different stream types can't
+// overlap either.
+const daemonSyncGroup = "records_sync"
+
+type daemonConfig struct {
+	Jobs []daemonJob `json:"jobs"`
+}
+
+func loadDaemonJobs(path string, newSyncService func() *service.SyncService) ([]scheduler.Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg daemonConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(cfg.Jobs) == 0 {
+		return nil, fmt.Errorf("%s: no jobs defined", path)
+	}
+	var jobs []scheduler.Job
+	for i, j := range cfg.Jobs {
+		if j.Name == "" {
+			j.Name = fmt.Sprintf("job-%d", i+1)
+		}
+		sched, err := scheduler.Parse(j.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", j.Name, err)
+		}
+		if _, err := checkStreamType(j.StreamType); err != nil {
+			return nil, fmt.Errorf("job %s: %w", j.Name, err)
+		}
+		if (j.Days == 0 && j.Hours == 0) || (j.Days != 0 && j.Hours != 0) {
+			return nil, fmt.Errorf("job %s: set either days or hours (only one)", j.Name)
+		}
+		overlapMin, minFreeGB := 60, 1.0
+		if j.OverlapMin != nil {
+			overlapMin = *j.OverlapMin
+		}
+		if j.MinFreeGB != nil {
+			minFreeGB = *j.MinFreeGB
+		}
+		if overlapMin < 0 {
+			return nil, fmt.Errorf("job %s: overlap_min must not be negative", j.Name)
+		}
+		if minFreeGB < 0 {
+			return nil, fmt.Errorf("job %s: min_free_gb must not be negative", j.Name)
+		}
+		args := service.Args{
+			StreamType:   j.StreamType,
+			StreamID:     -1,
+			Sync:         j.Sync,
+			AddMode:      j.AddMode,
+			NoTask:       j.NoTask,
+			MinFreeBytes: gbToBytes(minFreeGB),
+			Incremental:  j.Incremental,
+			Overlap:      time.Duration(overlapMin) * time.Minute,
+		}
+		if j.Days != 0 {
+			days := j.Days
+			args.AutoDays = &days
+		} else {
+			hours := j.Hours
+			args.AutoHours = &hours
+		}
+		name := j.Name
+		jobs = append(jobs, scheduler.Job{
+			Name:     j.Name,
+			Schedule: sched,
+			Group:    daemonSyncGroup,
+			Run: func(ctx context.Context) error {
+				// A fresh service per run keeps per-run state from leaking between runs.
+				svc := newSyncService()
+				svc.Actor = "daemon:" + name
+				svc.AuditTarget = "sync-cli daemon"
+				return svc.StartRecordProcessingContext(ctx, args, "auto")
+			},
+		})
+	}
+	return jobs, nil
+}
+
+// runDaemon implements `sync-cli daemon -jobs FILE`.
+func runDaemon(newSyncService func() *service.SyncService, args []string) {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	jobsPath := fs.String("jobs", "configs/sync-daemon.example.json", "jobs file (JSON)")
+	metricsAddr := fs.String("metrics_addr", "", "serve /metrics on this address (e.g. :9100); empty disables")
+	_ = fs.Parse(args)
+
+	jobs, err := loadDaemonJobs(*jobsPath, newSyncService)
+	if err != nil {
+		fmt.Println("Error loading jobs:", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		go func() {
+			if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+				log.Println("metrics server:", err)
+			}
+		}()
+	}
+	log.Printf("sync daemon started with %d jobs from %s", len(jobs), *jobsPath)
+	scheduler.New(jobs).Run(ctx)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"myproject/internal/service"
+)
+
+// runDedup implements `sync-cli dedup -start -end -stream_type [-stream_id] [-min_overlap F] [-strategy S] [--apply]`.
+func runDedup(svc *service.SyncService, args []string) {
+	fs := flag.NewFlagSet("dedup", flag.ExitOnError)
+	startStr := fs.String("start", "", `start datetime "YYYY-MM-DD HH:mm"`)
+	endStr := fs.String("end", "", `end datetime "YYYY-MM-DD HH:mm"`)
+	streamType := fs.String("stream_type", "", "stream type - `audio` or `video`")
+	streamID := fs.Int("stream_id", -1, "only stream with id")
+	minOverlap := fs.Float64("min_overlap", 0.5, "records overlapping by at least this share of the shorter one are duplicates")
+	strategy := fs.String("strategy", service.DedupByRate, "record to keep - `rate`, `duration` or `local`")
+	apply := fs.Bool("apply", false, "disable the duplicates (default: dry run)")
+	format := fs.String("format", "table", "output format - `table` or `json`")
+	_ = fs.Parse(args)
+
+	st, et := parseWindow(*startStr, *endStr)
+	stype := requireStreamType(*streamType)
+	if !service.ValidDedupStrategy(*strategy) {
+		fmt.Println("strategy - `rate`, `duration` or `local`")
+		os.Exit(1)
+	}
+	if *format != "table" && *format != "json" {
+		fmt.Println("format - `table` or `json`")
+		os.Exit(1)
+	}
+
+	clusters, err := svc.FindDuplicates(st, et, stype, *streamID, *minOverlap, *strategy)
+	if err != nil {
+		fmt.Println("Error finding duplicates:", err)
+		os.Exit(1)
+	}
+	duplicates := 0
+	for _, c := range clusters {
+		duplicates += len(c.Disable)
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(clusters)
+	} else {
+		const layout = "2006-01-02 15:04:05"
+		for _, c := range clusters {
+			fmt.Printf("stream %d\n", c.StreamID)
+			fmt.Printf("  keep    %-10d %s - %s  rate=%.3f score=%.2f  %s\n", c.Keep.RecordID,
+				c.Keep.Start.Format(layout), c.Keep.End.Format(layout), c.Keep.RecordRate, c.Keep.Score, c.Keep.Path)
+			for _, r := range c.Disable {
+				fmt.Printf("  disable %-10d %s - %s  rate=%.3f score=%.2f  %s\n", r.RecordID,
+					r.Start.Format(layout), r.End.Format(layout), r.RecordRate, r.Score, r.Path)
+			}
+		}
+		fmt.Printf("\n%d clusters, %d duplicate records\n", len(clusters), duplicates)
+	}
+
+	if !*apply {
+		if *format == "table" && duplicates > 0 {
+			fmt.Println("dry run: run with --apply to disable the duplicates")
+		}
+		return
+	}
+	disabled, err := svc.DisableDuplicates(clusters)
+	if err != nil {
+		fmt.Println("Error disabling duplicates:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("disabled %d records\n", disabled)
+}
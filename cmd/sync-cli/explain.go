@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"myproject/internal/service"
+)
+
+// runExplain implements `sync-cli explain -stream_id N -at "YYYY-MM-DD HH:mm" [--add_mode] [--log] [-format text|json]`.
+func runExplain(svc *service.SyncService, args []string) {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	streamID := fs.Int("stream_id", -1, "stream id")
+	atStr := fs.String("at", "", `datetime "YYYY-MM-DD HH:mm"`)
+	addMode := fs.Bool("add_mode", false, "try add mode when no better record is found for a gap")
+	showLog := fs.Bool("log", false, "print the sync log of the selection")
+	format := fs.String("format", "text", "output format - `text` or `json`")
+	_ = fs.Parse(args)
+
+	if *streamID < 0 || *atStr == "" {
+		fmt.Println("stream_id and at are required")
+		printHelp()
+		os.Exit(1)
+	}
+	at, err := validDateTimeType(*atStr)
+	if err != nil {
+		fmt.Println("Given Datetime not valid! Expected format, 'YYYY-MM-DD HH:mm'!")
+		os.Exit(1)
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Println("format - `text` or `json`")
+		os.Exit(1)
+	}
+
+	// The selection functions log to stdout; keep it out of the report.
+	stdout := os.Stdout
+	if !*showLog {
+		if devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+			os.Stdout = devNull
+			defer devNull.Close()
+		}
+	}
+	e, err := svc.Explain(*streamID, at, *addMode)
+	os.Stdout = stdout
+	if err != nil {
+		fmt.Println("Error explaining:", err)
+		os.Exit(1)
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(e)
+		return
+	}
+	const layout = "2006-01-02 15:04:05"
+	fmt.Printf("stream %d %s (%s) at %s\n", e.StreamID, e.StreamName, e.StreamType, e.At.Format(periodLayout))
+	fmt.Printf("sync window %s - %s\n", e.WindowStart.Format(layout), e.WindowEnd.Format(layout))
+	fmt.Printf("item: %s", e.Kind)
+	if !e.Start.IsZero() {
+		fmt.Printf(" %s - %s", e.Start.Format(layout), e.End.Format(layout))
+	}
+	if e.RecordID > 0 {
+		fmt.Printf(" (record %d)", e.RecordID)
+	}
+	fmt.Println()
+
+	if len(e.Servers) > 0 && e.Kind != service.ExplainCovered && e.Kind != service.ExplainShortGap {
+		fmt.Println("\nservers (import order):")
+		for _, sv := range e.Servers {
+			fmt.Printf("  %d. server %d: %d returned", sv.Position, sv.ServerID, sv.Returned)
+			if e.AddMode {
+				fmt.Printf(", %d in add mode", sv.ReturnedAddMode)
+			}
+			if sv.Error != "" {
+				fmt.Printf(", error: %s", sv.Error)
+			}
+			fmt.Println()
+		}
+		fmt.Println("\ncandidates:")
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  SERVER\tRECORD\tSTART\tEND\tDURATION\tRATE\tPERIOD RATE\tCOMPARE\tDECISION")
+		for _, c := range e.Candidates {
+			compare := "-"
+			if c.CompareRate != nil {
+				compare = fmt.Sprintf("%.2f", *c.CompareRate)
+			}
+			fmt.Fprintf(tw, "  %d\t%d\t%s\t%s\t%.2f\t%.3f\t%.3f\t%s\t%s\n", c.ServerID, c.RecordID,
+				c.Start.Format(layout), c.End.Format(layout), c.Duration, c.RecordRate, c.PeriodRate, compare, candidateDecision(c))
+		}
+		tw.Flush()
+	}
+
+	fmt.Println("\nsteps:")
+	for _, step := range e.Steps {
+		fmt.Println("  -", strings.TrimSpace(strings.ReplaceAll(step, "\n", "\n    ")))
+	}
+	fmt.Println("\nstatus:", e.Status, "(dry run, nothing was copied)")
+}
+
+func candidateDecision(c service.ExplainCandidate) string {
+	switch {
+	case c.Selected:
+		return "selected"
+	case c.Best:
+		return "best of server, lower compare_rate"
+	case c.Returned || c.ReturnedAddMode:
+		return "returned, lower period rate"
+	case len(c.Excluded) > 0 && len(c.ExcludedAddMode) > 0:
+		return "excluded: " + strings.Join(c.Excluded, ", ") + "; add mode: " + strings.Join(c.ExcludedAddMode, ", ")
+	case len(c.Excluded) > 0:
+		return "excluded: " + strings.Join(c.Excluded, ", ")
+	default:
+		return "not queried"
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"myproject/internal/service"
+)
+
+// runImportOrder implements `sync-cli import-order -stream_type [-days N | -start -end] [-stream_id] [-min_records N] [--apply]`.
+func runImportOrder(svc *service.SyncService, args []string) {
+	fs := flag.NewFlagSet("import-order", flag.ExitOnError)
+	startStr := fs.String("start", "", `start datetime "YYYY-MM-DD HH:mm"`)
+	endStr := fs.String("end", "", `end datetime "YYYY-MM-DD HH:mm"`)
+	days := fs.Int("days", 30, "history of the last N days (when -start/-end are not given)")
+	streamType := fs.String("stream_type", "", "stream type - `audio` or `video`")
+	streamID := fs.Int("stream_id", -1, "only stream with id")
+	minRecords := fs.Int("min_records", 10, "keep the current order of streams with fewer imported records")
+	apply := fs.Bool("apply", false, "write changed orders to streams.server_import_order")
+	format := fs.String("format", "table", "output format - `table` or `json`")
+	_ = fs.Parse(args)
+
+	var st, et time.Time
+	if *startStr != "" || *endStr != "" {
+		st, et = parseWindow(*startStr, *endStr)
+	} else {
+		if *days <= 0 {
+			fmt.Println("days must be positive")
+			os.Exit(1)
+		}
+		et = time.Now()
+		st = et.AddDate(0, 0, -*days)
+	}
+	stype := requireStreamType(*streamType)
+	if *format != "table" && *format != "json" {
+		fmt.Println("format - `table` or `json`")
+		os.Exit(1)
+	}
+
+	recs, err := svc.RecommendImportOrder(st, et, stype, *streamID, *minRecords)
+	if err != nil {
+		fmt.Println("Error recommending import order:", err)
+		os.Exit(1)
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(recs)
+	} else {
+		fmt.Printf("history %s - %s\n", st.Format(periodLayout), et.Format(periodLayout))
+		changed := 0
+		for _, r := range recs {
+			if !r.Changed {
+				continue
+			}
+			changed++
+			source := "stream"
+			if r.FromGeneral {
+				source = "general"
+			}
+			fmt.Printf("stream %d %s: %s (%s) -> %s\n", r.StreamID, r.StreamName, service.FormatServersOrder(r.Current), source, service.FormatServersOrder(r.Recommended))
+			for _, sup := range r.Supply {
+				fmt.Printf("    server %-3d records=%-5d minutes=%-9.2f avg_rate=%-6.3f score=%.2f\n",
+					sup.ServerID, sup.Records, sup.Minutes, sup.AvgRate, sup.Score)
+			}
+		}
+		fmt.Printf("%d of %d streams would change\n", changed, len(recs))
+	}
+
+	if !*apply {
+		return
+	}
+	updated, err := svc.ApplyImportOrder(recs)
+	fmt.Printf("updated server_import_order of %d streams\n", updated)
+	if err != nil {
+		fmt.Println("Error writing import order:", err)
+		os.Exit(1)
+	}
+}
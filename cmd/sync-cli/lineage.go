@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"myproject/internal/service"
+)
+
+// runLineage implements `sync-cli lineage <record-id>`.
+func runLineage(svc *service.SyncService, args []string) {
+	if len(args) != 1 {
+		fmt.Println("lineage needs exactly one record id")
+		printHelp()
+		os.Exit(1)
+	}
+	recordID, err := strconv.Atoi(args[0])
+	if err != nil || recordID <= 0 {
+		fmt.Println("record id must be a positive integer")
+		os.Exit(1)
+	}
+
+	hops, err := svc.Lineage(recordID)
+	for i, h := range hops {
+		r := h.Record
+		fmt.Printf("%2d. server %-3d record %-10d %s  %s - %s  rate=%.3f\n", i, h.ServerID, r.ID, r.Path,
+			r.StartedAt.Format("2006-01-02 15:04:05"), r.EndedAt.Format("2006-01-02 15:04:05"), r.RecordRate)
+		if r.ImportedSourceID > 0 {
+			fmt.Printf("    imported from server %d record %d\n", r.ImportedSourceID, r.ImportedRecordID)
+		}
+	}
+	if len(hops) > 0 {
+		first := hops[0]
+		originServerID, originRecordID := first.Record.Origin(first.ServerID)
+		fmt.Printf("origin: server %d record %d\n", originServerID, originRecordID)
+	}
+	if err != nil {
+		fmt.Println("lineage incomplete:", err)
+		os.Exit(1)
+	}
+}
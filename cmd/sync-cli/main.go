@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"os"
+	"os/user"
 	"time"
 
+	"myproject/internal/config"
 	"myproject/internal/repository"
 	"myproject/internal/service"
 	"myproject/internal/utils"
+	pkgutils "myproject/pkg/utils"
+
+	_ "github.com/lib/pq" // database.driver "postgres" of the audit log
 )
 
 func validDateTimeType(s string) (time.Time, error) {
@@ -22,15 +31,28 @@ func checkStreamType(value string) (string, error) {
 	return value, nil
 }
 
+func gbToBytes(gb float64) int64 {
+	return int64(gb * (1 << 30))
+}
+
 func printHelp() {
 	fmt.Println(`Usage:
-  program period -start "YYYY-MM-DD HH:mm" -end "YYYY-MM-DD HH:mm" -stream_type audio|video [--sync] [--add_mode] [--no_task] [-stream_id N]
-  program auto   -days N | -hours N -stream_type audio|video [--sync] [--add_mode] [--no_task] [-stream_id N]`)
+  program period -start "YYYY-MM-DD HH:mm" -end "YYYY-MM-DD HH:mm" -stream_type audio|video [--sync] [--add_mode] [--no_task] [-stream_id N] [-min_free_gb N]
+  program auto   -days N | -hours N -stream_type audio|video [--sync] [--add_mode] [--no_task] [-stream_id N] [-min_free_gb N] [--incremental [-overlap_min N]]
+  program lineage <record-id>
+  program daemon [-jobs FILE] [-metrics_addr :9100]
+  program coverage -start "YYYY-MM-DD HH:mm" -end "YYYY-MM-DD HH:mm" -stream_type audio|video [-stream_id N] [-format table|csv|json]
+  program compare  -start "YYYY-MM-DD HH:mm" -end "YYYY-MM-DD HH:mm" -stream_type audio|video [-stream_id N] [-format table|csv|json]
+  program import-order -stream_type audio|video [-days N | -start "YYYY-MM-DD HH:mm" -end "YYYY-MM-DD HH:mm"] [-stream_id N] [-min_records N] [--apply] [-format table|json]
+  program explain -stream_id N -at "YYYY-MM-DD HH:mm" [--add_mode] [--log] [-format text|json]
+  program verify -start "YYYY-MM-DD HH:mm" -end "YYYY-MM-DD HH:mm" -stream_type audio|video [-stream_id N] [--rehash] [-action report|disable|reimport] [-min_free_gb N] [-format table|json]
+  program orphans -start "YYYY-MM-DD HH:mm" -end "YYYY-MM-DD HH:mm" [-quarantine DIR] [-format table|json]
+  program dedup -start "YYYY-MM-DD HH:mm" -end "YYYY-MM-DD HH:mm" -stream_type audio|video [-stream_id N] [-min_overlap 0.5] [-strategy rate|duration|local] [--apply] [-format table|json]`)
 }
 
 func parseArgs() (service.Args, string) {
 	if len(os.Args) < 3 {
-		fmt.Println("Error: No argument specified.\n")
+		fmt.Println("Error: No argument specified.")
 		printHelp()
 		os.Exit(1)
 	}
@@ -49,6 +71,7 @@ func parseArgs() (service.Args, string) {
 		syncMode := fs.Bool("sync", false, "sync mode : update target database and copy files")
 		addMode := fs.Bool("add_mode", false, "add mode : add all records from another servers")
 		noTask := fs.Bool("no_task", false, "no task mode")
+		minFreeGB := fs.Float64("min_free_gb", 1, "stop copying when free space on the recording disk would drop below this many GB (0 disables)")
 		_ = fs.Parse(os.Args[2:])
 
 		if *startStr == "" || *endStr == "" {
@@ -84,6 +107,7 @@ func parseArgs() (service.Args, string) {
 			Sync:          *syncMode,
 			AddMode:       *addMode,
 			NoTask:        *noTask,
+			MinFreeBytes:  gbToBytes(*minFreeGB),
 		}
 		periodType = "period"
 
@@ -91,17 +115,28 @@ func parseArgs() (service.Args, string) {
 		fs := flag.NewFlagSet("auto", flag.ExitOnError)
 		autoDays := fs.Int("days", 0, "set days before for auto period")
 		autoHours := fs.Int("hours", 0, "set hours before for auto period")
+		incremental := fs.Bool("incremental", false, "incremental mode : start from the last successful run of this stream type")
+		overlapMin := fs.Int("overlap_min", 60, "incremental mode : minutes to re-scan before the watermark")
 		streamType := fs.String("stream_type", "", "stream type - `audio` or `video`")
 		streamID := fs.Int("stream_id", -1, "sync only stream with id")
 		syncMode := fs.Bool("sync", false, "sync mode : update target database and copy files")
 		addMode := fs.Bool("add_mode", false, "add mode : add all records from another servers")
 		noTask := fs.Bool("no_task", false, "no task mode")
+		minFreeGB := fs.Float64("min_free_gb", 1, "stop copying when free space on the recording disk would drop below this many GB (0 disables)")
 		_ = fs.Parse(os.Args[2:])
 
 		if (*autoDays == 0 && *autoHours == 0) || (*autoDays != 0 && *autoHours != 0) {
 			fmt.Println("You must set either days or hours (only one).")
 			os.Exit(1)
 		}
+		if *incremental && *streamID >= 0 {
+			fmt.Println("incremental mode syncs all streams of a type; stream_id is not allowed")
+			os.Exit(1)
+		}
+		if *overlapMin < 0 {
+			fmt.Println("overlap_min must not be negative")
+			os.Exit(1)
+		}
 		if *streamType == "" {
 			fmt.Println("stream_type is required")
 			os.Exit(1)
@@ -121,13 +156,16 @@ func parseArgs() (service.Args, string) {
 		}
 
 		a = service.Args{
-			AutoDays:   daysPtr,
-			AutoHours:  hoursPtr,
-			StreamType: stype,
-			StreamID:   *streamID,
-			Sync:       *syncMode,
-			AddMode:    *addMode,
-			NoTask:     *noTask,
+			AutoDays:     daysPtr,
+			AutoHours:    hoursPtr,
+			StreamType:   stype,
+			StreamID:     *streamID,
+			Sync:         *syncMode,
+			AddMode:      *addMode,
+			NoTask:       *noTask,
+			MinFreeBytes: gbToBytes(*minFreeGB),
+			Incremental:  *incremental,
+			Overlap:      time.Duration(*overlapMin) * time.Minute,
 		}
 		periodType = "auto"
 
@@ -140,39 +178,110 @@ func parseArgs() (service.Args, string) {
 	return a, periodType
 }
 
-// stubUtils is a no-op implementation of utils.Utils for when DB/Utils are not yet wired.
-type stubUtils struct{}
-
-func (stubUtils) GetParameter(db interface{}, key string) string                    { return "" }
-func (stubUtils) CopyFilesToDir(srcPattern, dstDir string, overwrite, printLog bool) bool { return false }
-func (stubUtils) BeginOfHour(t time.Time) time.Time                               { return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()) }
-func (stubUtils) EndOfHour(t time.Time) time.Time {
-	return stubUtils{}.BeginOfHour(t).Add(time.Hour).Add(-time.Nanosecond)
-}
-func (stubUtils) BeginOfDay(t time.Time) time.Time {
-	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+// cliActor names the OS user running sync-cli in the audit log.
+func cliActor() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	return "cli:" + pkgutils.CoalesceString(name, "unknown")
 }
-func (stubUtils) EndOfDay(t time.Time) time.Time {
-	return stubUtils{}.BeginOfDay(t).Add(24*time.Hour).Add(-time.Nanosecond)
+
+// openAuditLog opens the users DB of the myapp config (MYAPP_CONFIG, default
+// configs/config.yaml): its audit_log is the one GET /audit reads. Without a
+// config, a DSN or a reachable DB, runs are not audited.
+func openAuditLog() (*service.AuditService, *sql.DB) {
+	path := pkgutils.CoalesceString(os.Getenv("MYAPP_CONFIG"), "configs/config.yaml")
+	cfg, err := config.Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "warning: config %s not found; sync runs are not audited\n", path)
+		return nil, nil
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v; sync runs are not audited\n", err)
+		return nil, nil
+	}
+	if cfg.Database.DSN == "" {
+		fmt.Fprintf(os.Stderr, "warning: no database.dsn in %s; sync runs are not audited\n", path)
+		return nil, nil
+	}
+	db, err := sql.Open(cfg.Database.Driver, cfg.Database.DSN)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = db.PingContext(ctx)
+		cancel()
+		if err != nil {
+			db.Close()
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: audit log DB unavailable (%v); sync runs are not audited\n", err)
+		return nil, nil
+	}
+	return service.NewAuditService(repository.NewAuditRepo(db)), db
 }
-func (stubUtils) UpdateCompletionPercentage(db interface{}, taskID int, percent float64) {}
-func (stubUtils) CreateTask(db interface{}, taskType string, checkRunning bool) int     { return -1 }
-func (stubUtils) GetStreamNameByID(db interface{}, streamID int) string                 { return "" }
 
 func main() {
 	var localDB repository.DB = nil
 	getRemoteDB := func(serverID int) repository.DB {
 		return nil
 	}
-	var ut utils.Utils = stubUtils{}
+	var ut utils.Utils = utils.Stub{}
 
 	if localDB == nil {
 		fmt.Fprintln(os.Stderr, "warning: no DB set; provide repository.DB and utils.Utils in main to run sync")
 	}
 
-	svc := service.NewSyncService(localDB, getRemoteDB, ut)
+	audit, auditDB := openAuditLog()
+	if auditDB != nil {
+		defer auditDB.Close()
+	}
+	newSyncService := func() *service.SyncService {
+		svc := service.NewSyncService(localDB, getRemoteDB, ut)
+		svc.Paths = service.PathLayoutFromEnv(svc.Paths)
+		svc.Actor = cliActor()
+		svc.AuditTarget = "sync-cli"
+		if audit != nil {
+			svc.Audit = audit
+		}
+		return svc
+	}
+	svc := newSyncService()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lineage":
+			runLineage(svc, os.Args[2:])
+			return
+		case "daemon":
+			runDaemon(newSyncService, os.Args[2:])
+			return
+		case "coverage":
+			runCoverage(svc, os.Args[2:])
+			return
+		case "compare":
+			runCompare(svc, os.Args[2:])
+			return
+		case "import-order":
+			runImportOrder(svc, os.Args[2:])
+			return
+		case "explain":
+			runExplain(svc, os.Args[2:])
+			return
+		case "verify":
+			runVerify(svc, os.Args[2:])
+			return
+		case "orphans":
+			runOrphans(svc, os.Args[2:])
+			return
+		case "dedup":
+			runDedup(svc, os.Args[2:])
+			return
+		}
+	}
 	args, periodType := parseArgs()
 	fmt.Println(os.Args)
 	fmt.Printf("%+v\n", args)
-	svc.StartRecordProcessing(args, periodType)
+	if err := svc.StartRecordProcessing(args, periodType); err != nil {
+		os.Exit(1)
+	}
 }
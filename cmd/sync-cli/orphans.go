@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"myproject/internal/service"
+)
+
+// formatBytes prints n in the largest binary unit below it.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// runOrphans implements `sync-cli orphans -start -end [-quarantine DIR] [-format table|json]`.
+func runOrphans(svc *service.SyncService, args []string) {
+	fs := flag.NewFlagSet("orphans", flag.ExitOnError)
+	startStr := fs.String("start", "", `files modified from "YYYY-MM-DD HH:mm"`)
+	endStr := fs.String("end", "", `files modified until "YYYY-MM-DD HH:mm"`)
+	quarantine := fs.String("quarantine", "", "move orphan files into this directory (default: report only)")
+	format := fs.String("format", "table", "output format - `table` or `json`")
+	_ = fs.Parse(args)
+
+	st, et := parseWindow(*startStr, *endStr)
+	if *format != "table" && *format != "json" {
+		fmt.Println("format - `table` or `json`")
+		os.Exit(1)
+	}
+	var qdir string
+	if *quarantine != "" {
+		abs, err := filepath.Abs(*quarantine)
+		if err != nil {
+			fmt.Println("Invalid quarantine directory:", err)
+			os.Exit(1)
+		}
+		qdir = abs
+	}
+
+	// A partial scan is never quarantined: files of unscanned directories or
+	// unresolved owners would look like orphans.
+	report, err := svc.ScanOrphans(st, et, qdir)
+	if err != nil {
+		fmt.Println("Error scanning files:", err)
+		os.Exit(1)
+	}
+	if qdir != "" {
+		svc.QuarantineOrphans(report, qdir)
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "FILE\tSIZE\tMODIFIED\tREASON\tQUARANTINE")
+		for _, o := range report.Orphans {
+			moved := o.MovedTo
+			if o.Error != "" {
+				moved = "error: " + o.Error
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", o.Path, formatBytes(o.Size), o.ModTime.Format(periodLayout),
+				o.Reason, moved)
+		}
+		tw.Flush()
+		fmt.Printf("\nscanned %d files (%s) under %s\n", report.ScannedFiles, formatBytes(report.ScannedBytes), report.Root)
+		fmt.Printf("orphans: %d files (%s)\n", len(report.Orphans), formatBytes(report.OrphanBytes))
+		if qdir != "" {
+			fmt.Printf("moved to %s: %s\n", qdir, formatBytes(report.MovedBytes))
+		}
+	}
+	for _, o := range report.Orphans {
+		if o.Error != "" {
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"myproject/internal/service"
+)
+
+// runVerify implements `sync-cli verify -start -end -stream_type [-stream_id] [--rehash] [-action report|disable|reimport]`.
+func runVerify(svc *service.SyncService, args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	startStr := fs.String("start", "", `start datetime "YYYY-MM-DD HH:mm"`)
+	endStr := fs.String("end", "", `end datetime "YYYY-MM-DD HH:mm"`)
+	streamType := fs.String("stream_type", "", "stream type - `audio` or `video`")
+	streamID := fs.Int("stream_id", -1, "only stream with id")
+	rehash := fs.Bool("rehash", false, "read every file in full and compare imported files with their source")
+	action := fs.String("action", "report", "what to do with bad records - `report`, `disable` or `reimport`")
+	minFreeGB := fs.Float64("min_free_gb", 1, "keep at least N GB free when reimporting (0 disables the check)")
+	format := fs.String("format", "table", "output format - `table` or `json`")
+	_ = fs.Parse(args)
+
+	st, et := parseWindow(*startStr, *endStr)
+	stype := requireStreamType(*streamType)
+	switch *action {
+	case "report", "disable", "reimport":
+	default:
+		fmt.Println("action - `report`, `disable` or `reimport`")
+		os.Exit(1)
+	}
+	if *format != "table" && *format != "json" {
+		fmt.Println("format - `table` or `json`")
+		os.Exit(1)
+	}
+
+	report, err := svc.Verify(st, et, stype, *streamID, *rehash)
+	if err != nil {
+		fmt.Println("Error verifying records:", err)
+		os.Exit(1)
+	}
+	if len(report.Problems) > 0 {
+		switch *action {
+		case "disable":
+			err = svc.DisableVerified(report.Problems)
+		case "reimport":
+			err = svc.ReimportVerified(report.Problems, gbToBytes(*minFreeGB))
+		}
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "RECORD\tSTREAM\tSTART\tPROBLEM\tFILE\tDETAIL\tACTION")
+		for _, p := range report.Problems {
+			file := p.File
+			if file == "" {
+				file = p.Path
+			}
+			fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\t%s\t%s\n", p.RecordID, p.StreamID, p.Start.Format(periodLayout),
+				p.Problem, file, p.Detail, p.Action)
+		}
+		tw.Flush()
+		fmt.Printf("\nchecked %d records: %d missing, %d empty, %d corrupt, %d rejected paths\n", report.Checked,
+			report.Counts[service.VerifyMissing], report.Counts[service.VerifyEmpty],
+			report.Counts[service.VerifyCorrupt], report.Counts[service.VerifyRejectedPath])
+	}
+	if err != nil {
+		fmt.Println("Error applying action:", err)
+		os.Exit(1)
+	}
+	if *action == "report" && len(report.Problems) > 0 {
+		os.Exit(1)
+	}
+}
@@ -1,3 +1,5 @@
 module myproject
 
-go 1.21
+go 1.22
+
+require github.com/lib/pq v1.10.9
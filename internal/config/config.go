@@ -0,0 +1,167 @@
+// Package config loads the myapp configuration (configs/config.example.yaml).
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config is the myapp configuration.
+type Config struct {
+	Server   Server
+	Database Database
+	Logging  Logging
+}
+
+// Server configures the HTTP server.
+type Server struct {
+	Port               int
+	ReadTimeoutSec     int
+	WriteTimeoutSec    int
+	RequestTimeoutSec  int
+	ShutdownTimeoutSec int
+}
+
+// Database configures the users DB pool.
+type Database struct {
+	Driver             string
+	DSN                string
+	MaxOpenConns       int
+	MaxIdleConns       int
+	ConnMaxLifetimeMin int
+}
+
+// Logging configures the server log.
+type Logging struct {
+	Level  string
+	Format string
+}
+
+// Default returns the configuration used for keys missing from the file.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:               8080,
+			ReadTimeoutSec:     30,
+			WriteTimeoutSec:    30,
+			RequestTimeoutSec:  30,
+			ShutdownTimeoutSec: 30,
+		},
+		Database: Database{
+			Driver:             "postgres",
+			MaxOpenConns:       25,
+			MaxIdleConns:       5,
+			ConnMaxLifetimeMin: 5,
+		},
+		Logging: Logging{Level: "info", Format: "json"},
+	}
+}
+
+// Addr is the listen address of the HTTP server.
+func (s Server) Addr() string { return fmt.Sprintf(":%d", s.Port) }
+
+// ReadTimeout is the time allowed to read a whole request.
+func (s Server) ReadTimeout() time.Duration { return seconds(s.ReadTimeoutSec) }
+
+// WriteTimeout is the time allowed to write a response (event streams lift it).
+func (s Server) WriteTimeout() time.Duration { return seconds(s.WriteTimeoutSec) }
+
+// RequestTimeout is the time a handler gets before the client receives 503.
+func (s Server) RequestTimeout() time.Duration { return seconds(s.RequestTimeoutSec) }
+
+// ShutdownTimeout bounds draining requests and stopping sync runs on SIGTERM.
+func (s Server) ShutdownTimeout() time.Duration { return seconds(s.ShutdownTimeoutSec) }
+
+// ConnMaxLifetime is the maximum age of a pooled DB connection.
+func (d Database) ConnMaxLifetime() time.Duration {
+	return time.Duration(d.ConnMaxLifetimeMin) * time.Minute
+}
+
+func seconds(n int) time.Duration { return time.Duration(n) * time.Second }
+
+// Load reads the config file at path on top of Default.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	values, err := parseYAML(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg := Default()
+	for key, v := range values {
+		if err := cfg.set(key, v.value); err != nil {
+			return Config{}, fmt.Errorf("%s:%d: %s: %w", path, v.line, key, err)
+		}
+	}
+	if err := cfg.validate(); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// set assigns one "section.key" value. Unknown keys are errors so typos do not
+// silently fall back to defaults.
+func (c *Config) set(key, value string) error {
+	switch key {
+	case "server.port":
+		return setInt(&c.Server.Port, value)
+	case "server.read_timeout_sec":
+		return setInt(&c.Server.ReadTimeoutSec, value)
+	case "server.write_timeout_sec":
+		return setInt(&c.Server.WriteTimeoutSec, value)
+	case "server.request_timeout_sec":
+		return setInt(&c.Server.RequestTimeoutSec, value)
+	case "server.shutdown_timeout_sec":
+		return setInt(&c.Server.ShutdownTimeoutSec, value)
+	case "database.driver":
+		c.Database.Driver = value
+	case "database.dsn":
+		c.Database.DSN = value
+	case "database.max_open_conns":
+		return setInt(&c.Database.MaxOpenConns, value)
+	case "database.max_idle_conns":
+		return setInt(&c.Database.MaxIdleConns, value)
+	case "database.conn_max_lifetime_min":
+		return setInt(&c.Database.ConnMaxLifetimeMin, value)
+	case "logging.level":
+		c.Logging.Level = value
+	case "logging.format":
+		c.Logging.Format = value
+	default:
+		return fmt.Errorf("unknown key")
+	}
+	return nil
+}
+
+func setInt(dst *int, value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("must be an integer")
+	}
+	if n < 0 {
+		return fmt.Errorf("must not be negative")
+	}
+	*dst = n
+	return nil
+}
+
+func (c Config) validate() error {
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		return fmt.Errorf("server.port must be between 1 and 65535")
+	}
+	switch c.Logging.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("logging.level must be debug, info, warn or error")
+	}
+	switch c.Logging.Format {
+	case "json", "text":
+	default:
+		return fmt.Errorf("logging.format must be json or text")
+	}
+	return nil
+}
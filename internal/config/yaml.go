@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+type yamlValue struct {
+	value string
+	line  int
+}
+
+// parseYAML reads the subset of YAML the config file uses: top-level sections
+// holding "key: scalar" pairs, comments and optional quotes. The result is
+// keyed "section.key".
+func parseYAML(data []byte) (map[string]yamlValue, error) {
+	values := make(map[string]yamlValue)
+	section := ""
+	for i, raw := range strings.Split(string(data), "\n") {
+		n := i + 1
+		line := strings.TrimRight(stripComment(raw), " \t\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if strings.Contains(indent, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", n)
+		}
+		indented := indent != ""
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", n)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !indented {
+			if value != "" {
+				return nil, fmt.Errorf("line %d: top-level key %q must be a section", n, key)
+			}
+			section = key
+			continue
+		}
+		if section == "" {
+			return nil, fmt.Errorf("line %d: %q is not inside a section", n, key)
+		}
+		full := section + "." + key
+		if _, dup := values[full]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %s", n, full)
+		}
+		values[full] = yamlValue{value: unquote(value), line: n}
+	}
+	return values, nil
+}
+
+// stripComment cuts a "#" comment that is not inside quotes.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func unquote(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want map[string]string
+	}{
+		{
+			name: "sections",
+			in:   "server:\n  port: 8080\ndatabase:\n  driver: postgres\n",
+			want: map[string]string{"server.port": "8080", "database.driver": "postgres"},
+		},
+		{
+			name: "comments and blank lines",
+			in:   "# config\n\nserver: # HTTP\n  # the port\n  port: 8080 # default\n",
+			want: map[string]string{"server.port": "8080"},
+		},
+		{
+			name: "hash without leading space is kept",
+			in:   "database:\n  dsn: postgres://u:p#w@db/x\n",
+			want: map[string]string{"database.dsn": "postgres://u:p#w@db/x"},
+		},
+		{
+			name: "double quotes",
+			in:   "database:\n  dsn: \"host=db password=a # b\" # comment\n",
+			want: map[string]string{"database.dsn": "host=db password=a # b"},
+		},
+		{
+			name: "single quotes",
+			in:   "logging:\n  format: 'text'\n",
+			want: map[string]string{"logging.format": "text"},
+		},
+		{
+			name: "unbalanced quote is kept",
+			in:   "logging:\n  level: \"info\n",
+			want: map[string]string{"logging.level": "\"info"},
+		},
+		{
+			name: "value with colon",
+			in:   "server:\n  addr: localhost:8080\n",
+			want: map[string]string{"server.addr": "localhost:8080"},
+		},
+		{
+			name: "empty value",
+			in:   "database:\n  dsn:\n",
+			want: map[string]string{"database.dsn": ""},
+		},
+		{
+			name: "windows line endings",
+			in:   "server:\r\n  port: 8080\r\n",
+			want: map[string]string{"server.port": "8080"},
+		},
+		{
+			name: "same key in two sections",
+			in:   "server:\n  port: 1\nmetrics:\n  port: 2\n",
+			want: map[string]string{"server.port": "1", "metrics.port": "2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := parseYAML([]byte(tt.in))
+			if err != nil {
+				t.Fatalf("parseYAML: %v", err)
+			}
+			got := make(map[string]string, len(values))
+			for k, v := range values {
+				got[k] = v.value
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"tab indentation", "server:\n\tport: 8080\n", "line 2: tabs are not allowed"},
+		{"tab after spaces", "server:\n  \tport: 8080\n", "line 2: tabs are not allowed"},
+		{"duplicate key", "server:\n  port: 1\n  port: 2\n", "line 3: duplicate key server.port"},
+		{"duplicate key across repeated section", "server:\n  port: 1\nlogging:\n  level: info\nserver:\n  port: 2\n", "line 6: duplicate key server.port"},
+		{"top-level value", "port: 8080\n", "line 1: top-level key \"port\" must be a section"},
+		{"key outside a section", "  port: 8080\n", "line 1: \"port\" is not inside a section"},
+		{"missing colon", "server:\n  port 8080\n", "line 2: expected \"key: value\""},
+		{"empty key", "server:\n  : 8080\n", "line 2: expected \"key: value\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseYAML([]byte(tt.in))
+			if err == nil {
+				t.Fatalf("parseYAML succeeded, want error %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLLines(t *testing.T) {
+	values, err := parseYAML([]byte("# c\nserver:\n\n  port: 8080\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := values["server.port"].line; got != 4 {
+		t.Errorf("line = %d, want 4", got)
+	}
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"myproject/internal/middleware"
+	"myproject/internal/model"
+	"myproject/internal/service"
+)
+
+// maxAuditBody bounds the request body kept as audit parameters and the
+// error response read back for the audit entry.
+const maxAuditBody = 16 << 10
+
+// AuditHandler records mutating requests in the audit log and serves GET /audit.
+type AuditHandler struct {
+	svc *service.AuditService
+}
+
+// NewAuditHandler creates a new AuditHandler.
+func NewAuditHandler(svc *service.AuditService) *AuditHandler {
+	return &AuditHandler{svc: svc}
+}
+
+// auditRecorder keeps the status and, for errors, the start of the body.
+type auditRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *auditRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *auditRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if r.status >= 400 && r.body.Len() < maxAuditBody {
+		r.body.Write(b[:min(len(b), maxAuditBody-r.body.Len())])
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *auditRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Audited records action for every request to next: the token as actor, the
+// resource as target, the JSON body as parameters and the outcome from the
+// response status (with the error message of a failure).
+func (h *AuditHandler) Audited(action string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxAuditBody+1))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "reading request body: "+err.Error())
+			return
+		}
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+
+		rec := &auditRecorder{ResponseWriter: w}
+		next(rec, r)
+
+		e := model.AuditEntry{
+			Action:    action,
+			Target:    auditTarget(rec, r),
+			Params:    auditParams(body),
+			Outcome:   model.AuditSuccess,
+			RequestID: middleware.RequestIDFromContext(r.Context()),
+		}
+		t := TokenFromContext(r.Context())
+		e.Actor = actorName(t)
+		if t != nil {
+			e.ActorTokenID = &t.ID
+		}
+		if rec.status >= 400 {
+			e.Outcome = model.AuditFailure
+			var eb errorBody
+			if json.Unmarshal(rec.body.Bytes(), &eb) == nil && eb.Error != "" {
+				e.Error = eb.Error
+			} else {
+				e.Error = http.StatusText(rec.status)
+			}
+		}
+		h.svc.Record(e)
+	}
+}
+
+// auditTarget is the resource a request acted on: the Location of a created
+// resource, else the path up to the {id} parameter ("/sync/runs/5" for
+// "/sync/runs/5/cancel").
+func auditTarget(w http.ResponseWriter, r *http.Request) string {
+	if loc := w.Header().Get("Location"); loc != "" {
+		return loc
+	}
+	target := r.URL.Path
+	if id := r.PathValue("id"); id != "" {
+		if i := strings.Index(target, "/"+id); i >= 0 {
+			target = target[:i+1+len(id)]
+		}
+	}
+	return target
+}
+
+// auditParams keeps a JSON object body as is; anything else is left out.
+func auditParams(body []byte) json.RawMessage {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || len(body) > maxAuditBody || body[0] != '{' || !json.Valid(body) {
+		return nil
+	}
+	return body
+}
+
+// List handles GET /audit?actor=&action=&target=&outcome=&since=&until=&limit=&cursor=
+// — one page of audit entries, newest first, with the next page in
+// X-Next-Cursor and a Link rel="next" header.
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	p := service.ListAuditParams{
+		Cursor:  q.Get("cursor"),
+		Actor:   q.Get("actor"),
+		Action:  q.Get("action"),
+		Target:  q.Get("target"),
+		Outcome: q.Get("outcome"),
+	}
+	var fields []service.FieldError
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			fields = append(fields, service.FieldError{Field: "limit", Message: "must be an integer"})
+		}
+		p.Limit = n
+	}
+	for _, tf := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &p.Since}, {"until", &p.Until}} {
+		if v := q.Get(tf.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				fields = append(fields, service.FieldError{Field: tf.name, Message: "must be an RFC 3339 time like 2025-01-01T00:00:00Z"})
+			}
+			*tf.dst = t
+		}
+	}
+	if len(fields) > 0 {
+		writeBadRequest(w, &service.ValidationError{Fields: fields})
+		return
+	}
+	page, err := h.svc.List(p)
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			writeBadRequest(w, err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	entries := page.Entries
+	if entries == nil {
+		entries = []*model.AuditEntry{}
+	}
+	if page.NextCursor != "" {
+		q.Set("cursor", page.NextCursor)
+		w.Header().Set("X-Next-Cursor", page.NextCursor)
+		w.Header().Set("Link", fmt.Sprintf(`</audit?%s>; rel="next"`, q.Encode()))
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
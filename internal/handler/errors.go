@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"myproject/internal/service"
+)
+
+// errorBody is the JSON body of error responses. Fields is set for
+// validation errors, one entry per failing input field.
+type errorBody struct {
+	Error  string               `json:"error"`
+	Fields []service.FieldError `json:"fields,omitempty"`
+}
+
+// writeError writes msg as a JSON error body.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorBody{Error: msg})
+}
+
+// writeBadRequest writes a 400 JSON error body, with field details when err
+// is a *service.ValidationError.
+func writeBadRequest(w http.ResponseWriter, err error) {
+	var ve *service.ValidationError
+	if errors.As(err, &ve) {
+		writeJSON(w, http.StatusBadRequest, errorBody{Error: service.ErrValidation.Error(), Fields: ve.Fields})
+		return
+	}
+	writeError(w, http.StatusBadRequest, err.Error())
+}
+
+// decodeJSON decodes the request body into v, rejecting unknown fields and
+// trailing data. Errors name the offending field where possible.
+func decodeJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.Is(err, io.EOF):
+			return errors.New("request body is empty")
+		case errors.As(err, &typeErr):
+			return &service.ValidationError{Fields: []service.FieldError{
+				{Field: typeErr.Field, Message: fmt.Sprintf("must be a %s", typeErr.Type.Kind())},
+			}}
+		default:
+			return fmt.Errorf("invalid JSON body: %v", err)
+		}
+	}
+	if dec.More() {
+		return errors.New("invalid JSON body: unexpected data after the object")
+	}
+	return nil
+}
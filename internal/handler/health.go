@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"myproject/internal/service"
+)
+
+// checkTimeout bounds each readiness check.
+const checkTimeout = 2 * time.Second
+
+// HealthHandler serves liveness and readiness probes.
+type HealthHandler struct {
+	users  *service.UserService
+	health *service.HealthService
+}
+
+// NewHealthHandler creates a HealthHandler; health checks the sync dependencies.
+func NewHealthHandler(users *service.UserService, health *service.HealthService) *HealthHandler {
+	return &HealthHandler{users: users, health: health}
+}
+
+// Healthz handles GET /healthz — 200 while the process serves requests.
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": service.CheckOK})
+}
+
+// Readyz handles GET /readyz — 200 when the users DB and, if sync is
+// configured, the local records DB, every remote server DB and their
+// recording roots are reachable, else 503. The body lists the result of every
+// check.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ready := h.health.Readiness(r.Context(), checkTimeout, service.Check{Name: "users_db", Run: h.users.Ping})
+	status := http.StatusOK
+	if !ready.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, ready)
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"myproject/internal/metrics"
+	"myproject/internal/model"
+	"myproject/internal/service"
+)
+
+// Router registers the API routes on a ServeMux with method and path patterns
+// ("GET /users/{id}"). The mux answers unknown paths with 404 and known paths
+// with a wrong method with 405 and an Allow header listing the registered ones,
+// both with a JSON error body like the handlers write.
+type Router struct {
+	mux   *http.ServeMux
+	auth  *Authenticator
+	audit *AuditHandler
+
+	// streams holds the patterns registered with HandleStreamAs.
+	streams map[string]bool
+}
+
+// NewRouter creates an empty Router; auth guards the API routes and audit
+// records the mutating ones.
+func NewRouter(auth *Authenticator, audit *AuditHandler) *Router {
+	return &Router{mux: http.NewServeMux(), auth: auth, audit: audit, streams: make(map[string]bool)}
+}
+
+// Handle registers h for pattern, e.g. "POST /sync/runs" or "GET /records/{id}".
+// Requests are counted and timed under name in the HTTP metrics; an empty
+// name leaves the handler uninstrumented (long-lived streams).
+func (rt *Router) Handle(pattern, name string, h http.HandlerFunc) {
+	if name != "" {
+		h = metrics.InstrumentHandler(name, h)
+	}
+	rt.mux.Handle(pattern, h)
+}
+
+// ServeHTTP dispatches the request to the handler of the matching route.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, pattern := rt.mux.Handler(r); pattern == "" {
+		// No route: the mux writes a plain text 404 or 405 (with Allow).
+		rt.mux.ServeHTTP(&jsonErrorWriter{ResponseWriter: w}, r)
+		return
+	}
+	rt.mux.ServeHTTP(w, r)
+}
+
+// jsonErrorWriter replaces the plain text body of an error status with
+// errorBody, keeping the status and headers such as Allow.
+type jsonErrorWriter struct {
+	http.ResponseWriter
+	replaced bool
+}
+
+func (w *jsonErrorWriter) WriteHeader(status int) {
+	if status < 400 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.replaced = true
+	w.Header().Del("X-Content-Type-Options")
+	writeError(w.ResponseWriter, status, strings.ToLower(http.StatusText(status)))
+}
+
+func (w *jsonErrorWriter) Write(b []byte) (int, error) {
+	if w.replaced {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// HandleAs registers h like Handle, for tokens with role or a higher one.
+func (rt *Router) HandleAs(role, pattern, name string, h http.HandlerFunc) {
+	rt.Handle(pattern, name, rt.auth.Require(role, h))
+}
+
+// HandleStreamAs registers the long-lived stream h (e.g. Server-Sent Events)
+// like HandleAs, uninstrumented, and marks pattern for IsStream.
+func (rt *Router) HandleStreamAs(role, pattern string, h http.HandlerFunc) {
+	rt.HandleAs(role, pattern, "", h)
+	rt.streams[pattern] = true
+}
+
+// IsStream reports whether r is routed to a handler registered with
+// HandleStreamAs, which must not be cut by the request timeout.
+func (rt *Router) IsStream(r *http.Request) bool {
+	_, pattern := rt.mux.Handler(r)
+	return rt.streams[pattern]
+}
+
+// Users registers the users CRUD routes: reads for viewers, audited changes for admins.
+func (rt *Router) Users(h *UserHandler) {
+	rt.HandleAs(model.RoleViewer, "GET /users", "users", h.List)
+	rt.HandleAs(model.RoleAdmin, "POST /users", "users", rt.audit.Audited(service.AuditUserCreate, h.Create))
+	rt.HandleAs(model.RoleViewer, "GET /users/{id}", "user", h.Get)
+	rt.HandleAs(model.RoleAdmin, "PUT /users/{id}", "user", rt.audit.Audited(service.AuditUserUpdate, h.Update))
+	rt.HandleAs(model.RoleAdmin, "DELETE /users/{id}", "user", rt.audit.Audited(service.AuditUserDelete, h.Delete))
+}
+
+// SyncRuns registers the sync run routes: reads for viewers, audited control for operators.
+func (rt *Router) SyncRuns(h *SyncHandler) {
+	rt.HandleAs(model.RoleViewer, "GET /sync/runs", "sync_runs", h.List)
+	rt.HandleAs(model.RoleOperator, "POST /sync/runs", "sync_runs", rt.audit.Audited(service.AuditSyncRunStart, h.Start))
+	rt.HandleAs(model.RoleViewer, "GET /sync/runs/{id}", "sync_run", h.Get)
+	rt.HandleAs(model.RoleOperator, "POST /sync/runs/{id}/cancel", "sync_run_cancel", rt.audit.Audited(service.AuditSyncRunCancel, h.Cancel))
+	rt.HandleStreamAs(model.RoleViewer, "GET /sync/runs/{id}/events", h.Events)
+}
+
+// Audit registers the audit log query for admins.
+func (rt *Router) Audit() {
+	rt.HandleAs(model.RoleAdmin, "GET /audit", "audit", rt.audit.List)
+}
+
+// Health registers the liveness and readiness probes (no token needed).
+func (rt *Router) Health(h *HealthHandler) {
+	rt.Handle("GET /healthz", "healthz", h.Healthz)
+	rt.Handle("GET /readyz", "readyz", h.Readyz)
+}
+
+// pathID parses the {id} path parameter. An ID that is not a positive integer
+// cannot name a resource, so the response is 404.
+func pathID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusNotFound, "not found")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterErrors(t *testing.T) {
+	rt := NewRouter(nil, nil)
+	rt.Handle("GET /items/{id}", "", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"id": r.PathValue("id")})
+	})
+	rt.Handle("DELETE /items/{id}", "", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		wantCode  int
+		wantError string
+		wantAllow string
+	}{
+		{name: "unknown path", method: http.MethodGet, path: "/nope", wantCode: http.StatusNotFound, wantError: "not found"},
+		{name: "unknown subpath", method: http.MethodGet, path: "/items/1/extra", wantCode: http.StatusNotFound, wantError: "not found"},
+		{name: "wrong method", method: http.MethodPost, path: "/items/1", wantCode: http.StatusMethodNotAllowed,
+			wantError: "method not allowed", wantAllow: "DELETE, GET, HEAD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rt.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+			var body errorBody
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %q is not JSON: %v", rec.Body.String(), err)
+			}
+			if body.Error != tt.wantError {
+				t.Errorf("error = %q, want %q", body.Error, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestRouterMatchedRoute(t *testing.T) {
+	rt := NewRouter(nil, nil)
+	rt.Handle("GET /items/{id}", "", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"id": r.PathValue("id")})
+	})
+
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/42", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got, want := rec.Body.String(), "{\"id\":\"42\"}\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
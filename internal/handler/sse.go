@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"myproject/internal/service"
+)
+
+// sseHeartbeat keeps idle connections open through proxies.
+const sseHeartbeat = 15 * time.Second
+
+// Events handles GET /sync/runs/{id}/events: streams the run's progress as
+// Server-Sent Events until the run finishes or the client goes away.
+// A reconnecting client's Last-Event-ID resumes after that event.
+func (h *SyncHandler) Events(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	bus, err := h.runner.Events(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	// The controller reaches Flush through wrapping writers (metrics, logging).
+	flusher := http.NewResponseController(w)
+	// The stream lasts as long as the run; lift the server's write timeout.
+	_ = flusher.SetWriteDeadline(time.Time{})
+	var after int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		after, _ = strconv.ParseInt(v, 10, 64)
+	}
+	ch, unsubscribe := bus.Subscribe(after)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := flusher.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			_ = flusher.Flush()
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			writeEvent(w, e)
+			_ = flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e service.Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data)
+}
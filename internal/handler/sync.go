@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"myproject/internal/service"
+)
+
+// SyncHandler exposes sync runs over HTTP and delegates to the SyncRunner.
+type SyncHandler struct {
+	runner *service.SyncRunner
+}
+
+// NewSyncHandler creates a new SyncHandler.
+func NewSyncHandler(runner *service.SyncRunner) *SyncHandler {
+	return &SyncHandler{runner: runner}
+}
+
+// Defaults of the sync-cli flags for fields omitted from startSyncRunRequest.
+const (
+	defaultMinFreeGB  = 1.0
+	defaultOverlapMin = 60
+)
+
+// startSyncRunRequest is the body of POST /sync/runs; fields mirror the sync-cli
+// flags, including their defaults when omitted.
+type startSyncRunRequest struct {
+	PeriodType  string   `json:"period_type"`
+	Start       string   `json:"start"`
+	End         string   `json:"end"`
+	Days        int      `json:"days"`
+	Hours       int      `json:"hours"`
+	StreamType  string   `json:"stream_type"`
+	StreamID    *int     `json:"stream_id"`
+	Sync        bool     `json:"sync"`
+	AddMode     bool     `json:"add_mode"`
+	NoTask      bool     `json:"no_task"`
+	MinFreeGB   *float64 `json:"min_free_gb"`
+	Incremental bool     `json:"incremental"`
+	OverlapMin  *int     `json:"overlap_min"`
+}
+
+func (req startSyncRunRequest) args() (service.Args, error) {
+	minFreeGB, overlapMin := defaultMinFreeGB, defaultOverlapMin
+	if req.MinFreeGB != nil {
+		minFreeGB = *req.MinFreeGB
+	}
+	if req.OverlapMin != nil {
+		overlapMin = *req.OverlapMin
+	}
+	if minFreeGB < 0 {
+		return service.Args{}, fmt.Errorf("min_free_gb must not be negative")
+	}
+	if overlapMin < 0 {
+		return service.Args{}, fmt.Errorf("overlap_min must not be negative")
+	}
+	a := service.Args{
+		StreamType:   req.StreamType,
+		StreamID:     -1,
+		Sync:         req.Sync,
+		AddMode:      req.AddMode,
+		NoTask:       req.NoTask,
+		MinFreeBytes: int64(minFreeGB * (1 << 30)),
+		Incremental:  req.Incremental,
+		Overlap:      time.Duration(overlapMin) * time.Minute,
+	}
+	if req.StreamID != nil {
+		a.StreamID = *req.StreamID
+	}
+	if req.Start != "" {
+		t, err := time.ParseInLocation("2006-01-02 15:04", req.Start, time.Local)
+		if err != nil {
+			return a, fmt.Errorf("start: expected format 'YYYY-MM-DD HH:mm'")
+		}
+		a.StartDatetime = t
+	}
+	if req.End != "" {
+		t, err := time.ParseInLocation("2006-01-02 15:04", req.End, time.Local)
+		if err != nil {
+			return a, fmt.Errorf("end: expected format 'YYYY-MM-DD HH:mm'")
+		}
+		a.EndDatetime = t
+	}
+	if req.Days != 0 {
+		days := req.Days
+		a.AutoDays = &days
+	}
+	if req.Hours != 0 {
+		hours := req.Hours
+		a.AutoHours = &hours
+	}
+	return a, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// List handles GET /sync/runs — runs, newest first.
+func (h *SyncHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.runner.List())
+}
+
+// Start handles POST /sync/runs — starts a run in the background, 202 with its Location.
+func (h *SyncHandler) Start(w http.ResponseWriter, r *http.Request) {
+	var req startSyncRunRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	args, err := req.args()
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	run, err := h.runner.Start(args, req.PeriodType, actorName(TokenFromContext(r.Context())))
+	if errors.Is(err, service.ErrRunnerClosed) {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrRunActive) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/sync/runs/%d", run.ID))
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+// Get handles GET /sync/runs/{id} — the run and its report.
+func (h *SyncHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	run, err := h.runner.Get(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// Cancel handles POST /sync/runs/{id}/cancel.
+func (h *SyncHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	run, err := h.runner.Cancel(id)
+	switch {
+	case errors.Is(err, service.ErrRunNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrRunNotRunning):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeJSON(w, http.StatusAccepted, run)
+	}
+}
@@ -1,8 +1,10 @@
 package handler
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"myproject/internal/model"
 	"myproject/internal/service"
@@ -18,20 +20,126 @@ func NewUserHandler(svc *service.UserService) *UserHandler {
 	return &UserHandler{svc: svc}
 }
 
-// List handles GET /users — calls service and returns JSON.
+// List handles GET /users?limit=&cursor=&active=&email=&q=&sort= — one page of
+// users as a JSON array. The cursor of the next page is in the X-Next-Cursor
+// header and a Link rel="next" header.
 func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	q := r.URL.Query()
+	p := service.ListUsersParams{
+		Cursor: q.Get("cursor"),
+		Email:  q.Get("email"),
+		Query:  q.Get("q"),
+		Sort:   q.Get("sort"),
+	}
+	var fields []service.FieldError
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			fields = append(fields, service.FieldError{Field: "limit", Message: "must be an integer"})
+		}
+		p.Limit = n
+	}
+	if v := q.Get("active"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			fields = append(fields, service.FieldError{Field: "active", Message: "must be true or false"})
+		}
+		p.Active = &b
+	}
+	if len(fields) > 0 {
+		writeBadRequest(w, &service.ValidationError{Fields: fields})
 		return
 	}
-	users, err := h.svc.List()
+	page, err := h.svc.List(p)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeUserError(w, err)
 		return
 	}
+	users := page.Users
 	if users == nil {
 		users = []*model.User{}
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(users)
+	if page.NextCursor != "" {
+		q.Set("cursor", page.NextCursor)
+		w.Header().Set("X-Next-Cursor", page.NextCursor)
+		w.Header().Set("Link", fmt.Sprintf(`</users?%s>; rel="next"`, q.Encode()))
+	}
+	writeJSON(w, http.StatusOK, users)
+}
+
+// Create handles POST /users — 201 with the new user and its Location.
+func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var in model.CreateUserInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	u, err := h.svc.Create(in)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/users/%d", u.ID))
+	writeJSON(w, http.StatusCreated, u)
+}
+
+// Get handles GET /users/{id}.
+func (h *UserHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	u, err := h.svc.Get(id)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+// Update handles PUT /users/{id}; fields missing from the body are left unchanged.
+func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var in model.UpdateUserInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	u, err := h.svc.Update(id, in)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+// Delete handles DELETE /users/{id} — 204 on success.
+func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.svc.Delete(id); err != nil {
+		writeUserError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeUserError maps user service errors to the status codes of the API spec.
+func writeUserError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrValidation):
+		writeBadRequest(w, err)
+	case errors.Is(err, service.ErrEmailTaken):
+		writeJSON(w, http.StatusConflict, errorBody{Error: err.Error(),
+			Fields: []service.FieldError{{Field: "email", Message: "is already in use"}}})
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
 }
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	httpRequests = NewCounterVec("http_requests_total",
+		"HTTP requests by handler, method and status code.", "handler", "method", "code")
+	httpDuration = NewHistogramVec("http_request_duration_seconds",
+		"HTTP request latency by handler and method.", nil, "handler", "method")
+)
+
+// statusRecorder remembers the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// InstrumentHandler counts requests and observes latency for next under the given handler name.
+func InstrumentHandler(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		httpRequests.Inc(name, r.Method, strconv.Itoa(rec.status))
+		httpDuration.Observe(time.Since(start).Seconds(), name, r.Method)
+	}
+}
@@ -0,0 +1,263 @@
+// Package metrics implements counters and histograms with label values and
+// writes them in the Prometheus text exposition format (version 0.0.4).
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are the default histogram buckets, in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Collector is a metric family that can write itself in text format.
+type Collector interface {
+	Name() string
+	Write(w io.Writer)
+}
+
+// Registry holds collectors and renders them sorted by name.
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]Collector
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]Collector)}
+}
+
+// Default is the registry used by the package-level constructors and Handler.
+var Default = NewRegistry()
+
+// MustRegister adds collectors; it panics on duplicate names.
+func (r *Registry) MustRegister(cs ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range cs {
+		if _, ok := r.collectors[c.Name()]; ok {
+			panic("metrics: duplicate metric " + c.Name())
+		}
+		r.collectors[c.Name()] = c
+	}
+}
+
+// Write writes every registered metric family to w.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.collectors))
+	for n := range r.collectors {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	cs := make([]Collector, len(names))
+	for i, n := range names {
+		cs[i] = r.collectors[n]
+	}
+	r.mu.Unlock()
+	for _, c := range cs {
+		c.Write(w)
+	}
+}
+
+// Handler serves the registry in text exposition format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// Handler serves the Default registry.
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// CounterVec is a counter partitioned by label values.
+type CounterVec struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labelValues []string
+	v           float64
+}
+
+// NewCounterVec creates a CounterVec and registers it with Default.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: make(map[string]*counterValue)}
+	Default.MustRegister(c)
+	return c
+}
+
+// Name returns the metric name.
+func (c *CounterVec) Name() string { return c.name }
+
+// Inc adds 1 for the given label values.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v (which must not be negative) for the given label values.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	key := labelKey(c.name, c.labels, labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cv, ok := c.values[key]
+	if !ok {
+		cv = &counterValue{labelValues: append([]string(nil), labelValues...)}
+		c.values[key] = cv
+	}
+	cv.v += v
+}
+
+// Write writes the counter family.
+func (c *CounterVec) Write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeHeader(w, c.name, c.help, "counter")
+	for _, key := range sortedKeys(c.values) {
+		cv := c.values[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, cv.labelValues, "", ""), formatFloat(cv.v))
+	}
+}
+
+// HistogramVec is a histogram partitioned by label values.
+type HistogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	values map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labelValues []string
+	counts      []uint64 // per bucket, not cumulative
+	count       uint64
+	sum         float64
+}
+
+// NewHistogramVec creates a HistogramVec with the given upper bounds (nil means
+// DefBuckets) and registers it with Default.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: b, values: make(map[string]*histogramValue)}
+	Default.MustRegister(h)
+	return h
+}
+
+// Name returns the metric name.
+func (h *HistogramVec) Name() string { return h.name }
+
+// Observe records v for the given label values.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := labelKey(h.name, h.labels, labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{labelValues: append([]string(nil), labelValues...), counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+	for i, ub := range h.buckets {
+		if v <= ub {
+			hv.counts[i]++
+			break
+		}
+	}
+	hv.count++
+	hv.sum += v
+}
+
+// Write writes the histogram family with cumulative buckets.
+func (h *HistogramVec) Write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(w, h.name, h.help, "histogram")
+	for _, key := range sortedKeys(h.values) {
+		hv := h.values[key]
+		var cumulative uint64
+		for i, ub := range h.buckets {
+			cumulative += hv.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, hv.labelValues, "le", formatFloat(ub)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, hv.labelValues, "le", "+Inf"), hv.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, hv.labelValues, "", ""), formatFloat(hv.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, hv.labelValues, "", ""), hv.count)
+	}
+}
+
+func labelKey(name string, labels, values []string) string {
+	if len(labels) != len(values) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", name, len(labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeHeader(w io.Writer, name, help, typ string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels renders {a="x",b="y"} plus an optional extra label (used for le).
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, n, labelValueEscaper.Replace(values[i]))
+	}
+	if extraName != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, extraName, extraValue)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
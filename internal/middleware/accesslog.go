@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// AccessLog writes one structured log entry per request with method, path,
+// status, latency, response bytes and request ID.
+func AccessLog(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := record(w)
+			defer func() {
+				status := rec.status
+				if status == 0 {
+					status = http.StatusOK
+				}
+				logger.LogAttrs(r.Context(), slog.LevelInfo, "http request",
+					slog.String("request_id", RequestIDFromContext(r.Context())),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Int("status", status),
+					slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+					slog.Int64("bytes", rec.bytes),
+					slog.String("remote_addr", r.RemoteAddr),
+				)
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Package middleware provides composable net/http middleware for myapp:
+// request IDs, access logs, panic recovery and per-request timeouts.
+package middleware
+
+import "net/http"
+
+// Middleware wraps a handler with extra behaviour.
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h with mws; the first middleware is the outermost, so
+// Chain(h, a, b) serves a(b(h)).
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// responseRecorder remembers the status code and body size written by a
+// handler. Middlewares share one recorder per request (see record).
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// record returns w as a responseRecorder, wrapping it only once.
+func record(w http.ResponseWriter) *responseRecorder {
+	if rec, ok := w.(*responseRecorder); ok {
+		return rec
+	}
+	return &responseRecorder{ResponseWriter: w}
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// written reports whether the response header has been sent.
+func (r *responseRecorder) written() bool {
+	return r.status != 0
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover turns a panic in a handler into a 500 response with the request ID,
+// so the client can quote it and the logged stack can be found. If the handler
+// had already started the response, the connection is aborted instead.
+// Place it inside AccessLog so the 500 is logged.
+func Recover(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := record(w)
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				id := RequestIDFromContext(r.Context())
+				logger.Error("panic serving request",
+					slog.String("request_id", id),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("panic", fmt.Sprint(p)),
+					slog.String("stack", string(debug.Stack())),
+				)
+				if rec.written() {
+					panic(http.ErrAbortHandler)
+				}
+				rec.Header().Set("Content-Type", "application/json")
+				rec.WriteHeader(http.StatusInternalServerError)
+				_ = json.NewEncoder(rec).Encode(map[string]string{
+					"error":      "internal server error",
+					"request_id": id,
+				})
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// timeoutBody is the 503 body of a request that ran out of time.
+const timeoutBody = `{"error":"request timed out"}`
+
+// Timeout limits each request to d: the request context is cancelled at the
+// deadline and the client gets a 503 JSON error if the handler has not
+// finished by then. The response is buffered until the handler returns, so
+// requests for which exempt reports true (long-lived streams such as SSE) are
+// passed through unchanged. d <= 0 disables the timeout.
+func Timeout(d time.Duration, exempt func(*http.Request) bool) Middleware {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exempt != nil && exempt(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			serveWithTimeout(w, r, next, d)
+		})
+	}
+}
+
+// serveWithTimeout runs next into a buffer and copies it to w, or answers 503
+// when d passes first; later writes of the handler fail with
+// http.ErrHandlerTimeout. A panic of the handler is re-raised here, so the
+// Recover middleware still sees it.
+func serveWithTimeout(w http.ResponseWriter, r *http.Request, next http.Handler, d time.Duration) {
+	ctx, cancel := context.WithTimeout(r.Context(), d)
+	defer cancel()
+	tw := &timeoutWriter{h: make(http.Header)}
+	done := make(chan struct{})
+	panicked := make(chan interface{}, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panicked <- p
+				return
+			}
+			close(done)
+		}()
+		next.ServeHTTP(tw, r.WithContext(ctx))
+	}()
+
+	select {
+	case p := <-panicked:
+		panic(p)
+	case <-done:
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+		dst := w.Header()
+		for k, vv := range tw.h {
+			dst[k] = vv
+		}
+		if tw.code == 0 {
+			tw.code = http.StatusOK
+		}
+		w.WriteHeader(tw.code)
+		_, _ = w.Write(tw.buf.Bytes())
+	case <-ctx.Done():
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+		tw.timedOut = true
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = io.WriteString(w, timeoutBody+"\n")
+			return
+		}
+		// The client went away; nobody reads the body.
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+// timeoutWriter buffers the response of a handler under Timeout.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	h        http.Header
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.h }
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.code == 0 {
+		tw.code = http.StatusOK
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.code != 0 {
+		return
+	}
+	tw.code = code
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("late"))
+	})
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("ok"))
+	})
+	exempt := func(r *http.Request) bool { return r.URL.Path == "/events" }
+
+	tests := []struct {
+		name     string
+		h        http.Handler
+		path     string
+		wantCode int
+		wantType string
+		wantBody string
+	}{
+		{name: "timed out", h: slow, path: "/slow", wantCode: http.StatusServiceUnavailable,
+			wantType: "application/json", wantBody: timeoutBody + "\n"},
+		{name: "in time", h: fast, path: "/fast", wantCode: http.StatusCreated,
+			wantType: "text/plain", wantBody: "ok"},
+		{name: "exempt", h: fast, path: "/events", wantCode: http.StatusCreated,
+			wantType: "text/plain", wantBody: "ok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Timeout(20*time.Millisecond, exempt)(tt.h)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestTimeoutExemptIsNotCut(t *testing.T) {
+	h := Timeout(10*time.Millisecond, func(*http.Request) bool { return true })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(30 * time.Millisecond)
+			if err := r.Context().Err(); err != nil {
+				t.Errorf("context of exempt request: %v", err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
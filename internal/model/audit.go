@@ -0,0 +1,39 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit outcomes.
+const (
+	AuditSuccess   = "success"
+	AuditFailure   = "failure"
+	AuditCancelled = "cancelled"
+)
+
+// AuditEntry represents a row from the audit_log table.
+type AuditEntry struct {
+	ID           int64           `json:"id"`
+	Time         time.Time       `json:"time"`
+	Actor        string          `json:"actor"`
+	ActorTokenID *int64          `json:"actor_token_id,omitempty"`
+	Action       string          `json:"action"`
+	Target       string          `json:"target"`
+	Params       json.RawMessage `json:"params,omitempty"`
+	Outcome      string          `json:"outcome"`
+	Error        string          `json:"error,omitempty"`
+	RequestID    string          `json:"request_id,omitempty"`
+}
+
+// AuditFilter selects audit entries, newest first. Empty fields match everything.
+type AuditFilter struct {
+	Actor    string
+	Action   string
+	Target   string
+	Outcome  string
+	Since    time.Time // created_at >= Since
+	Until    time.Time // created_at < Until
+	BeforeID int64     // keyset: only entries with a smaller id
+	Limit    int
+}
@@ -4,7 +4,7 @@ import "time"
 
 // Period represents a time range for a stream (recorded or non-recorded).
 type Period struct {
-	Start    time.Time
-	End      time.Time
-	StreamID int
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	StreamID int       `json:"stream_id,omitempty"`
 }
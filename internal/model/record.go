@@ -23,6 +23,8 @@ type Record struct {
 
 	ImportedRecordID int
 	ImportedSourceID int
+	OriginRecordID   int
+	OriginServerID   int
 
 	SamplingRate int
 	FrameWidth   int
@@ -35,3 +37,16 @@ type Record struct {
 	IsRecordChecked bool
 	IsDeleted       bool
 }
+
+// Origin returns the server and record ID where r was first recorded, given
+// that r was read from serverID. Rows imported before provenance was tracked
+// fall back to their immediate import source.
+func (r Record) Origin(serverID int) (int, int) {
+	if r.OriginServerID > 0 {
+		return r.OriginServerID, r.OriginRecordID
+	}
+	if r.ImportedSourceID > 0 {
+		return r.ImportedSourceID, r.ImportedRecordID
+	}
+	return serverID, r.ID
+}
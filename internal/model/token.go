@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Roles of API tokens, from least to most privileged.
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// APIToken represents a row from the api_tokens table. The token itself is
+// never stored, only its hash.
+type APIToken struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Role      string     `json:"role"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
@@ -1,7 +1,40 @@
 package model
 
+import "time"
+
 // User represents the domain entity (no DB or HTTP logic).
 type User struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreateUserInput is the body of POST /users. Active defaults to true.
+type CreateUserInput struct {
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Active *bool  `json:"active"`
+}
+
+// UpdateUserInput is the body of PUT /users/{id}; nil fields are left unchanged.
+type UpdateUserInput struct {
+	Name   *string `json:"name"`
+	Email  *string `json:"email"`
+	Active *bool   `json:"active"`
+}
+
+// UserFilter selects one page of users, ordered by id.
+type UserFilter struct {
+	Active *bool
+	// Email matches the whole address.
+	Email string
+	// Query matches a case-insensitive substring of name or email.
+	Query string
+	// AfterID continues after this id in the sort order; 0 starts at the first page.
+	AfterID int64
+	Desc    bool
+	Limit   int
 }
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"myproject/internal/model"
+)
+
+const auditColumns = "id, created_at, actor, actor_token_id, action, target, params, outcome, error, request_id"
+
+// auditInsertSQL casts params so drivers may pass the JSON as text.
+const auditInsertSQL = `INSERT INTO audit_log (actor, actor_token_id, action, target, params, outcome, error, request_id)
+VALUES ($1, $2, $3, $4, $5::jsonb, $6, $7, $8)`
+
+// AuditRepo talks to the database for the audit log (append and query only).
+type AuditRepo struct {
+	db *sql.DB
+}
+
+// NewAuditRepo creates a new AuditRepo.
+func NewAuditRepo(db *sql.DB) *AuditRepo {
+	return &AuditRepo{db: db}
+}
+
+// auditArgs are the auditInsertSQL arguments of e.
+func auditArgs(e model.AuditEntry) []interface{} {
+	params := string(e.Params)
+	if params == "" {
+		params = "{}"
+	}
+	var tokenID interface{}
+	if e.ActorTokenID != nil {
+		tokenID = *e.ActorTokenID
+	}
+	return []interface{}{e.Actor, tokenID, e.Action, e.Target, params, e.Outcome, e.Error, e.RequestID}
+}
+
+// Insert appends e to the audit log.
+func (r *AuditRepo) Insert(e model.AuditEntry) error {
+	if r.db == nil {
+		return ErrNoDB
+	}
+	_, err := r.db.Exec(auditInsertSQL, auditArgs(e)...)
+	return err
+}
+
+// auditListQuery builds the SELECT for f with only the predicates in use.
+func auditListQuery(f model.AuditFilter) (string, []interface{}) {
+	var where []string
+	var args []interface{}
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Actor != "" {
+		add("actor = $%d", f.Actor)
+	}
+	if f.Action != "" {
+		add("action = $%d", f.Action)
+	}
+	if f.Target != "" {
+		add("target = $%d", f.Target)
+	}
+	if f.Outcome != "" {
+		add("outcome = $%d", f.Outcome)
+	}
+	if !f.Since.IsZero() {
+		add("created_at >= $%d", f.Since)
+	}
+	if !f.Until.IsZero() {
+		add("created_at < $%d", f.Until)
+	}
+	if f.BeforeID > 0 {
+		add("id < $%d", f.BeforeID)
+	}
+	q := "SELECT " + auditColumns + " FROM audit_log"
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, f.Limit)
+	q += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+	return q, args
+}
+
+// List returns the entries matching f, newest first.
+func (r *AuditRepo) List(f model.AuditFilter) ([]*model.AuditEntry, error) {
+	if r.db == nil {
+		return nil, nil
+	}
+	q, args := auditListQuery(f)
+	rows, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []*model.AuditEntry
+	for rows.Next() {
+		var e model.AuditEntry
+		var tokenID sql.NullInt64
+		var params []byte
+		if err := rows.Scan(&e.ID, &e.Time, &e.Actor, &tokenID, &e.Action, &e.Target,
+			&params, &e.Outcome, &e.Error, &e.RequestID); err != nil {
+			return nil, err
+		}
+		if tokenID.Valid {
+			e.ActorTokenID = &tokenID.Int64
+		}
+		e.Params = params
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
@@ -3,18 +3,22 @@ package repository
 import "myproject/internal/model"
 
 // InsertRecord inserts a record into the given DB with is_record_approved=true, processed=false.
+// The origin columns keep the server and record where r was first recorded.
 func InsertRecord(d DB, r model.Record, sourceServerID int) error {
+	originServerID, originRecordID := r.Origin(sourceServerID)
 	sql := `
 insert into records (
 	stream_id, path, started_at, ended_at, duration, duration_recorded, return_code,
 	stream_type, url_index, is_record_approved, processed, converted_to_mp3,
 	converted_to_low, record_rate, imported_record_id, imported_source_id,
-	sampling_rate, frame_width, shape, fps, frame_step, v_shape, is_preprocessed
+	sampling_rate, frame_width, shape, fps, frame_step, v_shape, is_preprocessed,
+	origin_server_id, origin_record_id
 ) values (
 	$1,$2,$3,$4,$5,$6,$7,
 	$8,$9,$10,$11,$12,
 	$13,$14,$15,$16,
-	$17,$18,$19,$20,$21,$22,$23
+	$17,$18,$19,$20,$21,$22,$23,
+	$24,$25
 )`
 	_, err := d.Insert(sql,
 		r.StreamID,
@@ -40,6 +44,8 @@ insert into records (
 		r.FrameStep,
 		r.VShape,
 		r.IsPreprocessed,
+		originServerID,
+		originRecordID,
 	)
 	return err
 }
@@ -0,0 +1,7 @@
+package repository
+
+// UpdateStreamServerImportOrder sets streams.server_import_order for one stream.
+func UpdateStreamServerImportOrder(d DB, streamID int, order string) error {
+	sql := "update streams set server_import_order = $1 where id = $2"
+	return d.Update(sql, order, streamID)
+}
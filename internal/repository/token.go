@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"myproject/internal/model"
+)
+
+const tokenColumns = "id, name, role, created_at, revoked_at"
+
+// TokenRepo talks to the database for API tokens.
+type TokenRepo struct {
+	db *sql.DB
+}
+
+// NewTokenRepo creates a new TokenRepo.
+func NewTokenRepo(db *sql.DB) *TokenRepo {
+	return &TokenRepo{db: db}
+}
+
+func scanToken(row rowScanner) (*model.APIToken, error) {
+	var t model.APIToken
+	var revoked sql.NullTime
+	if err := row.Scan(&t.ID, &t.Name, &t.Role, &t.CreatedAt, &revoked); err != nil {
+		return nil, err
+	}
+	if revoked.Valid {
+		t.RevokedAt = &revoked.Time
+	}
+	return &t, nil
+}
+
+// Create stores a token by its hash.
+func (r *TokenRepo) Create(name, role, hash string) (*model.APIToken, error) {
+	if r.db == nil {
+		return nil, ErrNoDB
+	}
+	t, err := scanToken(r.db.QueryRow(
+		"INSERT INTO api_tokens (name, role, token_hash) VALUES ($1, $2, $3) RETURNING "+tokenColumns,
+		name, role, hash))
+	if err != nil {
+		return nil, writeError(err)
+	}
+	return t, nil
+}
+
+// GetActiveByHash returns the token with hash unless it is revoked, or ErrNotFound.
+func (r *TokenRepo) GetActiveByHash(hash string) (*model.APIToken, error) {
+	if r.db == nil {
+		return nil, ErrNoDB
+	}
+	t, err := scanToken(r.db.QueryRow(
+		"SELECT "+tokenColumns+" FROM api_tokens WHERE token_hash = $1 AND revoked_at IS NULL", hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return t, err
+}
+
+// List returns all tokens, revoked ones included, by id.
+func (r *TokenRepo) List() ([]*model.APIToken, error) {
+	if r.db == nil {
+		return nil, ErrNoDB
+	}
+	rows, err := r.db.Query("SELECT " + tokenColumns + " FROM api_tokens ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tokens []*model.APIToken
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// Revoke marks token id revoked; ErrNotFound if it does not exist or is already revoked.
+func (r *TokenRepo) Revoke(id int64) error {
+	if r.db == nil {
+		return ErrNoDB
+	}
+	res, err := r.db.Exec("UPDATE api_tokens SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
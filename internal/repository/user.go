@@ -1,25 +1,295 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
 	"myproject/internal/model"
 )
 
+var (
+	// ErrNotFound is returned when the requested row does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrNoDB is returned by writes when no database is configured.
+	ErrNoDB = errors.New("no database configured")
+	// ErrDuplicate is returned when a write violates a unique constraint.
+	ErrDuplicate = errors.New("duplicate value")
+)
+
+const userColumns = "id, name, email, active, created_at, updated_at"
+
+// User queries, prepared once per UserRepo.
+const (
+	userGetSQL    = "SELECT " + userColumns + " FROM users WHERE id = $1"
+	userCreateSQL = "INSERT INTO users (name, email, active) VALUES ($1, $2, $3) RETURNING " + userColumns
+	userUpdateSQL = `UPDATE users
+SET name = COALESCE($2, name), email = COALESCE($3, email), active = COALESCE($4, active), updated_at = NOW()
+WHERE id = $1
+RETURNING ` + userColumns
+	userDeleteSQL = "DELETE FROM users WHERE id = $1"
+)
+
 // UserRepo talks to the database for users (pure CRUD).
 type UserRepo struct {
 	db *sql.DB
+
+	// listStmts caches the prepared list query of each filter combination.
+	listMu    sync.Mutex
+	listStmts map[string]*sql.Stmt
+
+	once    sync.Once
+	prepErr error
+	get     *sql.Stmt
+	create  *sql.Stmt
+	update  *sql.Stmt
+	delete  *sql.Stmt
 }
 
-// NewUserRepo creates a new UserRepo.
+// NewUserRepo creates a new UserRepo. Statements are prepared on first use.
 func NewUserRepo(db *sql.DB) *UserRepo {
 	return &UserRepo{db: db}
 }
 
-// List returns all users from the database.
-func (r *UserRepo) List() ([]*model.User, error) {
+func (r *UserRepo) prepare() error {
+	r.once.Do(func() {
+		for _, p := range []struct {
+			stmt **sql.Stmt
+			sql  string
+		}{
+			{&r.get, userGetSQL},
+			{&r.create, userCreateSQL},
+			{&r.update, userUpdateSQL},
+			{&r.delete, userDeleteSQL},
+		} {
+			stmt, err := r.db.Prepare(p.sql)
+			if err != nil {
+				r.prepErr = fmt.Errorf("prepare %q: %w", p.sql, err)
+				return
+			}
+			*p.stmt = stmt
+		}
+	})
+	return r.prepErr
+}
+
+// Close closes the prepared statements.
+func (r *UserRepo) Close() error {
+	var firstErr error
+	stmts := []*sql.Stmt{r.get, r.create, r.update, r.delete}
+	r.listMu.Lock()
+	for _, stmt := range r.listStmts {
+		stmts = append(stmts, stmt)
+	}
+	r.listStmts = nil
+	r.listMu.Unlock()
+	for _, stmt := range stmts {
+		if stmt == nil {
+			continue
+		}
+		if err := stmt.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Ping checks that the database answers.
+func (r *UserRepo) Ping(ctx context.Context) error {
+	if r.db == nil {
+		return ErrNoDB
+	}
+	return r.db.PingContext(ctx)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (*model.User, error) {
+	u := &model.User{}
+	if err := row.Scan(&u.ID, &u.Name, &u.Email, &u.Active, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique_violation
+// (SQLSTATE 23505). Drivers expose the code differently, so the SQLState
+// method of pgx and lib/pq is tried before the message.
+func isUniqueViolation(err error) bool {
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		return state.SQLState() == "23505"
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "23505") || strings.Contains(msg, "duplicate key value violates unique constraint")
+}
+
+// writeError maps driver errors of a write to the repository errors.
+func writeError(err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound
+	case isUniqueViolation(err):
+		return fmt.Errorf("%w: %v", ErrDuplicate, err)
+	default:
+		return err
+	}
+}
+
+// listQuery builds the list query of f with only the predicates in use, so
+// the planner can pick idx_users_active / idx_users_email and walk the
+// primary key for the keyset order. It returns the query and its arguments.
+func listQuery(f model.UserFilter) (string, []interface{}) {
+	var where []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if f.Active != nil {
+		where = append(where, "active = "+arg(*f.Active))
+	}
+	if f.Email != "" {
+		where = append(where, "email = "+arg(f.Email))
+	}
+	if f.Query != "" {
+		// Substring match, served by the trigram indexes of migration 004.
+		p := arg("%" + escapeLike(f.Query) + "%")
+		where = append(where, "(name ILIKE "+p+" OR email ILIKE "+p+")")
+	}
+	order, after := "ASC", "id > "
+	if f.Desc {
+		order, after = "DESC", "id < "
+	}
+	if f.AfterID > 0 {
+		where = append(where, after+arg(f.AfterID))
+	}
+	q := "SELECT " + userColumns + " FROM users"
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	q += " ORDER BY id " + order + " LIMIT " + arg(f.Limit)
+	return q, args
+}
+
+// escapeLike escapes the LIKE wildcards of s (default escape character \).
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (r *UserRepo) listStmt(query string) (*sql.Stmt, error) {
+	r.listMu.Lock()
+	defer r.listMu.Unlock()
+	if stmt, ok := r.listStmts[query]; ok {
+		return stmt, nil
+	}
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("prepare %q: %w", query, err)
+	}
+	if r.listStmts == nil {
+		r.listStmts = make(map[string]*sql.Stmt)
+	}
+	r.listStmts[query] = stmt
+	return stmt, nil
+}
+
+// List returns up to f.Limit users matching f, ordered by id.
+func (r *UserRepo) List(f model.UserFilter) ([]*model.User, error) {
 	if r.db == nil {
 		return []*model.User{}, nil
 	}
-	// TODO: rows, err := r.db.Query("SELECT id, name FROM users"); ...
-	return []*model.User{}, nil
+	query, args := listQuery(f)
+	stmt, err := r.listStmt(query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := []*model.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// Get returns the user with the given id, or ErrNotFound.
+func (r *UserRepo) Get(id int64) (*model.User, error) {
+	if r.db == nil {
+		return nil, ErrNotFound
+	}
+	if err := r.prepare(); err != nil {
+		return nil, err
+	}
+	u, err := scanUser(r.get.QueryRow(id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return u, err
+}
+
+// Create inserts a user and returns the stored row. A taken email gives ErrDuplicate.
+func (r *UserRepo) Create(u *model.User) (*model.User, error) {
+	if r.db == nil {
+		return nil, ErrNoDB
+	}
+	if err := r.prepare(); err != nil {
+		return nil, err
+	}
+	created, err := scanUser(r.create.QueryRow(u.Name, u.Email, u.Active))
+	if err != nil {
+		return nil, writeError(err)
+	}
+	return created, nil
+}
+
+// Update sets the non-nil fields of in on user id and returns the updated
+// user. It returns ErrNotFound for an unknown id and ErrDuplicate for a taken email.
+func (r *UserRepo) Update(id int64, in model.UpdateUserInput) (*model.User, error) {
+	if r.db == nil {
+		return nil, ErrNoDB
+	}
+	if err := r.prepare(); err != nil {
+		return nil, err
+	}
+	u, err := scanUser(r.update.QueryRow(id, in.Name, in.Email, in.Active))
+	if err != nil {
+		return nil, writeError(err)
+	}
+	return u, nil
+}
+
+// Delete removes user id, or returns ErrNotFound.
+func (r *UserRepo) Delete(id int64) error {
+	if r.db == nil {
+		return ErrNoDB
+	}
+	if err := r.prepare(); err != nil {
+		return err
+	}
+	res, err := r.delete.Exec(id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
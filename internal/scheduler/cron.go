@@ -0,0 +1,179 @@
+// Package scheduler runs jobs on cron-like schedules inside a long-lived process.
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule returns the next activation time strictly after t.
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// every fires at a fixed interval, aligned to the interval boundaries.
+type every struct {
+	d time.Duration
+}
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Truncate(e.d).Add(e.d)
+}
+
+// cronSchedule is a standard 5-field cron expression: minute hour dom month dow.
+// Each field is a bit set of the allowed values.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar / dowStar record a field starting with `*` (`*`, `*/2`); cron
+	// matches dom OR dow only when neither does.
+	domStar, dowStar bool
+}
+
+type fieldRange struct {
+	min, max int
+}
+
+var (
+	minuteRange = fieldRange{0, 59}
+	hourRange   = fieldRange{0, 23}
+	domRange    = fieldRange{1, 31}
+	monthRange  = fieldRange{1, 12}
+	dowRange    = fieldRange{0, 7}
+)
+
+var macros = map[string]string{
+	"@yearly":  "0 0 1 1 *",
+	"@monthly": "0 0 1 * *",
+	"@weekly":  "0 0 * * 0",
+	"@daily":   "0 0 * * *",
+	"@hourly":  "0 * * * *",
+}
+
+// Parse parses a 5-field cron expression ("*/15 * * * *", "5 1-6 * * 1-5"),
+// one of the macros @yearly, @monthly, @weekly, @daily, @hourly, or
+// "@every <duration>" (e.g. "@every 30m").
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", spec, err)
+		}
+		if d < time.Minute {
+			return nil, fmt.Errorf("schedule %q: interval must be at least 1m", spec)
+		}
+		return every{d: d}, nil
+	}
+	if m, ok := macros[spec]; ok {
+		spec = m
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q: expected 5 fields, got %d", spec, len(fields))
+	}
+	var c cronSchedule
+	var err error
+	if c.minute, err = parseField(fields[0], minuteRange); err != nil {
+		return nil, fmt.Errorf("schedule %q: minute: %w", spec, err)
+	}
+	if c.hour, err = parseField(fields[1], hourRange); err != nil {
+		return nil, fmt.Errorf("schedule %q: hour: %w", spec, err)
+	}
+	if c.dom, err = parseField(fields[2], domRange); err != nil {
+		return nil, fmt.Errorf("schedule %q: day of month: %w", spec, err)
+	}
+	if c.month, err = parseField(fields[3], monthRange); err != nil {
+		return nil, fmt.Errorf("schedule %q: month: %w", spec, err)
+	}
+	if c.dow, err = parseField(fields[4], dowRange); err != nil {
+		return nil, fmt.Errorf("schedule %q: day of week: %w", spec, err)
+	}
+	// 7 is an alias for Sunday.
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = strings.HasPrefix(fields[2], "*")
+	c.dowStar = strings.HasPrefix(fields[4], "*")
+	return c, nil
+}
+
+// parseField parses a comma-separated list of `*`, `n`, `a-b`, with an optional `/step`.
+func parseField(field string, r fieldRange) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart, step = part[:i], s
+		}
+		lo, hi := r.min, r.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			v, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			lo = v
+			if step == 1 {
+				hi = v
+			}
+		}
+		if lo < r.min || hi > r.max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, r.min, r.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (c cronSchedule) dayMatches(t time.Time) bool {
+	domOK := c.dom&(1<<uint(t.Day())) != 0
+	dowOK := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
+
+// Next returns the first matching minute after t, or the zero time when
+// nothing matches within five years (e.g. "0 0 30 2 *").
+func (c cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseField(t *testing.T) {
+	bits := func(vs ...int) uint64 {
+		var b uint64
+		for _, v := range vs {
+			b |= 1 << uint(v)
+		}
+		return b
+	}
+	tests := []struct {
+		field string
+		r     fieldRange
+		want  uint64
+	}{
+		{"*", hourRange, bits(0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23)},
+		{"5", minuteRange, bits(5)},
+		{"1-5", dowRange, bits(1, 2, 3, 4, 5)},
+		{"*/15", minuteRange, bits(0, 15, 30, 45)},
+		{"10-20/5", minuteRange, bits(10, 15, 20)},
+		{"50/5", minuteRange, bits(50, 55)},
+		{"1,15,31", domRange, bits(1, 15, 31)},
+		{"1-3,10-11", monthRange, bits(1, 2, 3, 10, 11)},
+		{"0,*/12", hourRange, bits(0, 12)},
+		{"*/2", domRange, bits(1, 3, 5, 7, 9, 11, 13, 15, 17, 19, 21, 23, 25, 27, 29, 31)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got, err := parseField(tt.field, tt.r)
+			if err != nil {
+				t.Fatalf("parseField: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %b, want %b", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"* * * *", "expected 5 fields, got 4"},
+		{"* * * * * *", "expected 5 fields, got 6"},
+		{"60 * * * *", "minute: \"60\" out of range 0-59"},
+		{"* 24 * * *", "hour: \"24\" out of range 0-23"},
+		{"* * 0 * *", "day of month: \"0\" out of range 1-31"},
+		{"* * * 13 *", "month: \"13\" out of range 1-12"},
+		{"* * * * 8", "day of week: \"8\" out of range 0-7"},
+		{"5-1 * * * *", "minute: \"5-1\" out of range"},
+		{"*/0 * * * *", "minute: invalid step"},
+		{"*/x * * * *", "minute: invalid step"},
+		{"a-5 * * * *", "minute: invalid range"},
+		{"x * * * *", "minute: invalid value"},
+		{"@every 30s", "interval must be at least 1m"},
+		{"@every soon", "invalid duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := Parse(tt.spec)
+			if err == nil {
+				t.Fatalf("Parse succeeded, want error %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	at := func(s string) time.Time {
+		t, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+	// 2025-01-01 is a Wednesday.
+	tests := []struct {
+		spec string
+		from string
+		want string
+	}{
+		{"* * * * *", "2025-01-01 10:00", "2025-01-01 10:01"},
+		{"*/15 * * * *", "2025-01-01 10:07", "2025-01-01 10:15"},
+		{"*/15 * * * *", "2025-01-01 10:45", "2025-01-01 11:00"},
+		{"5 1-6 * * *", "2025-01-01 06:05", "2025-01-02 01:05"},
+		{"0 9,17 * * *", "2025-01-01 09:00", "2025-01-01 17:00"},
+		{"30 2 * * 1-5", "2025-01-03 03:00", "2025-01-06 02:30"},
+		{"0 0 * * 7", "2025-01-01 00:00", "2025-01-05 00:00"},
+		{"0 0 * * 0", "2025-01-01 00:00", "2025-01-05 00:00"},
+		{"0 0 31 * *", "2025-01-31 00:00", "2025-03-31 00:00"},
+		{"0 0 29 2 *", "2025-01-01 00:00", "2028-02-29 00:00"},
+		// Day of month and day of week both restricted: either matches.
+		{"0 0 15 * 1", "2025-01-01 00:00", "2025-01-06 00:00"},
+		{"0 0 15 * 1", "2025-01-13 00:00", "2025-01-15 00:00"},
+		// One of them is *: only the other restricts.
+		{"0 0 15 * *", "2025-01-01 00:00", "2025-01-15 00:00"},
+		{"0 0 * * 1", "2025-01-01 00:00", "2025-01-06 00:00"},
+		// A stepped * counts as * for that rule: both fields must match, not either.
+		{"0 0 */2 * 1", "2025-01-01 00:00", "2025-01-13 00:00"},
+		{"0 0 1 * */2", "2025-01-01 00:00", "2025-02-01 00:00"},
+		{"@daily", "2025-01-01 12:00", "2025-01-02 00:00"},
+		{"@hourly", "2025-01-01 12:30", "2025-01-01 13:00"},
+		{"@weekly", "2025-01-01 12:00", "2025-01-05 00:00"},
+		{"@monthly", "2025-01-01 00:00", "2025-02-01 00:00"},
+		{"@yearly", "2025-01-01 00:00", "2026-01-01 00:00"},
+		{"@every 30m", "2025-01-01 10:10", "2025-01-01 10:30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec+" from "+tt.from, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := s.Next(at(tt.from)); !got.Equal(at(tt.want)) {
+				t.Errorf("Next = %s, want %s", got.Format("2006-01-02 15:04 Mon"), tt.want)
+			}
+		})
+	}
+}
+
+func TestNextNever(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Next(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("Next = %s, want zero time", got)
+	}
+}
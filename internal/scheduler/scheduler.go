@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Job is a named unit of work run on a schedule.
+type Job struct {
+	Name     string
+	Schedule Schedule
+	// Group names a set of jobs that must never run at the same time
+	// (e.g. all jobs sharing a task lock). Empty means the job name.
+	Group string
+	Run   func(ctx context.Context) error
+}
+
+// Scheduler runs jobs on their schedules until its context is cancelled.
+// A job whose group is still busy when it is due skips that activation.
+type Scheduler struct {
+	jobs []Job
+
+	mu     sync.Mutex
+	groups map[string]*sync.Mutex
+	wg     sync.WaitGroup
+}
+
+// New creates a Scheduler for the given jobs.
+func New(jobs []Job) *Scheduler {
+	return &Scheduler{jobs: jobs, groups: make(map[string]*sync.Mutex)}
+}
+
+func (s *Scheduler) groupLock(j Job) *sync.Mutex {
+	name := j.Group
+	if name == "" {
+		name = j.Name
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.groups[name]
+	if !ok {
+		m = &sync.Mutex{}
+		s.groups[name] = m
+	}
+	return m
+}
+
+// Run starts all jobs and blocks until ctx is cancelled and every running job
+// has returned. Jobs see the same ctx, so they can stop early on shutdown.
+func (s *Scheduler) Run(ctx context.Context) {
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, j)
+	}
+	<-ctx.Done()
+	log.Println("scheduler: shutting down, waiting for running jobs")
+	s.wg.Wait()
+	log.Println("scheduler: stopped")
+}
+
+func (s *Scheduler) loop(ctx context.Context, j Job) {
+	defer s.wg.Done()
+	lock := s.groupLock(j)
+	for {
+		next := j.Schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("scheduler: job %s has no next activation, disabled", j.Name)
+			return
+		}
+		log.Printf("scheduler: job %s next run at %s", j.Name, next.Format("2006-01-02 15:04:05"))
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if !lock.TryLock() {
+			log.Printf("scheduler: job %s skipped, group is still running", j.Name)
+			continue
+		}
+		s.runOnce(ctx, j)
+		lock.Unlock()
+	}
+}
+
+// runOnce runs j and keeps the scheduler alive if it fails or panics.
+func (s *Scheduler) runOnce(ctx context.Context, j Job) {
+	start := time.Now()
+	log.Printf("scheduler: job %s started", j.Name)
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+			}
+		}()
+		return j.Run(ctx)
+	}()
+	if err != nil {
+		log.Printf("scheduler: job %s failed after %v: %v", j.Name, time.Since(start), err)
+		return
+	}
+	log.Printf("scheduler: job %s finished in %v", j.Name, time.Since(start))
+}
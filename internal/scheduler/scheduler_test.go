@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tick fires every few milliseconds so tests don't wait for whole minutes.
+type tick struct{}
+
+func (tick) Next(t time.Time) time.Time { return t.Add(5 * time.Millisecond) }
+
+// runFor runs s until d has passed and it has stopped.
+func runFor(s *Scheduler, d time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	s.Run(ctx)
+}
+
+func TestSchedulerSkipsBusyGroup(t *testing.T) {
+	var mu sync.Mutex
+	active := make(map[string]int)
+	maxActive := make(map[string]int)
+	var runs, otherRuns atomic.Int32
+	job := func(name, group string, counter *atomic.Int32) Job {
+		return Job{Name: name, Group: group, Schedule: tick{}, Run: func(ctx context.Context) error {
+			counter.Add(1)
+			mu.Lock()
+			active[group]++
+			maxActive[group] = max(maxActive[group], active[group])
+			mu.Unlock()
+			time.Sleep(30 * time.Millisecond)
+			mu.Lock()
+			active[group]--
+			mu.Unlock()
+			return nil
+		}}
+	}
+	runFor(New([]Job{
+		job("audio", "records_sync", &runs),
+		job("video", "records_sync", &runs),
+		job("report", "", &otherRuns),
+	}), 200*time.Millisecond)
+
+	if maxActive["records_sync"] != 1 {
+		t.Errorf("jobs of one group ran %d at a time, want 1", maxActive["records_sync"])
+	}
+	// Each run takes 30ms and activations come every 5ms: most of them are skipped.
+	if n := runs.Load(); n == 0 || n > 8 {
+		t.Errorf("group ran %d times in 200ms, want 1-8", n)
+	}
+	if otherRuns.Load() == 0 {
+		t.Error("job of another group never ran while the group was busy")
+	}
+}
+
+func TestSchedulerRecoversPanic(t *testing.T) {
+	var runs atomic.Int32
+	s := New([]Job{{Name: "flaky", Schedule: tick{}, Run: func(ctx context.Context) error {
+		if runs.Add(1) == 1 {
+			panic("boom")
+		}
+		return nil
+	}}})
+	done := make(chan struct{})
+	go func() {
+		runFor(s, 100*time.Millisecond)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scheduler did not stop after a job panicked")
+	}
+	if n := runs.Load(); n < 2 {
+		t.Errorf("job ran %d times, want it scheduled again after the panic", n)
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"myproject/internal/model"
+	"myproject/internal/repository"
+	pkgutils "myproject/pkg/utils"
+)
+
+// Audit actions. Sync runs are audited by SyncService when they finish,
+// whoever started them (API, sync-cli or daemon); requests refused with 401
+// or 403 are audited as AuditAuthDenied.
+const (
+	AuditUserCreate    = "user.create"
+	AuditUserUpdate    = "user.update"
+	AuditUserDelete    = "user.delete"
+	AuditSyncRunStart  = "sync_run.start"
+	AuditSyncRunCancel = "sync_run.cancel"
+	AuditSyncRunFinish = "sync_run.finish"
+	AuditAuthDenied    = "auth.denied"
+)
+
+// Auditor stores audit entries. Recording must never fail the audited
+// operation, so implementations log their own errors.
+type Auditor interface {
+	Record(e model.AuditEntry)
+}
+
+// AuditFunc adapts a function to Auditor.
+type AuditFunc func(e model.AuditEntry)
+
+// Record calls f(e).
+func (f AuditFunc) Record(e model.AuditEntry) { f(e) }
+
+// AuditService records and queries the audit log.
+type AuditService struct {
+	repo *repository.AuditRepo
+}
+
+// NewAuditService creates a new AuditService.
+func NewAuditService(repo *repository.AuditRepo) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record appends e to the audit log; a failed write is logged.
+func (s *AuditService) Record(e model.AuditEntry) {
+	if err := s.repo.Insert(e); err != nil {
+		log.Printf("audit: %s %s by %s (%s) not recorded: %v", e.Action, e.Target, e.Actor, e.Outcome, err)
+	}
+}
+
+// auditParams marshals v for AuditEntry.Params.
+func auditParams(v interface{}) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return json.RawMessage(fmt.Sprintf(`{"marshal_error":%q}`, err.Error()))
+	}
+	return b
+}
+
+// auditRun records the outcome of a StartRecordProcessing run when s.Audit is set.
+func (s *SyncService) auditRun(args Args, periodType string, err error) {
+	if s.Audit == nil {
+		return
+	}
+	e := model.AuditEntry{
+		Actor:   pkgutils.CoalesceString(s.Actor, "system"),
+		Action:  AuditSyncRunFinish,
+		Target:  pkgutils.CoalesceString(s.AuditTarget, "sync"),
+		Outcome: model.AuditSuccess,
+		Params: auditParams(map[string]interface{}{
+			"period_type": periodType,
+			"args":        args,
+			"report":      s.Report(),
+		}),
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		e.Outcome, e.Error = model.AuditCancelled, err.Error()
+	case err != nil:
+		e.Outcome, e.Error = model.AuditFailure, err.Error()
+	}
+	s.Audit.Record(e)
+}
+
+// Audit list page sizes.
+const (
+	DefaultAuditPageSize = 100
+	MaxAuditPageSize     = 500
+)
+
+// ListAuditParams are the query parameters of GET /audit.
+type ListAuditParams struct {
+	// Limit is the page size; 0 means DefaultAuditPageSize.
+	Limit int
+	// Cursor is the NextCursor of the previous page.
+	Cursor  string
+	Actor   string
+	Action  string
+	Target  string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+}
+
+// AuditPage is one page of audit entries, newest first; NextCursor is empty on the last page.
+type AuditPage struct {
+	Entries    []*model.AuditEntry
+	NextCursor string
+}
+
+// auditCursorSort tags audit cursors, so user cursors are not accepted.
+const auditCursorSort = "audit"
+
+// List returns one page of audit entries matching p, newest first.
+func (s *AuditService) List(p ListAuditParams) (*AuditPage, error) {
+	var v validator
+	f := model.AuditFilter{
+		Actor:  strings.TrimSpace(p.Actor),
+		Action: strings.TrimSpace(p.Action),
+		Target: strings.TrimSpace(p.Target),
+		Since:  p.Since,
+		Until:  p.Until,
+		Limit:  p.Limit,
+	}
+	if f.Limit == 0 {
+		f.Limit = DefaultAuditPageSize
+	}
+	if f.Limit < 1 || f.Limit > MaxAuditPageSize {
+		v.add("limit", fmt.Sprintf("must be between 1 and %d", MaxAuditPageSize))
+	}
+	switch p.Outcome {
+	case "", model.AuditSuccess, model.AuditFailure, model.AuditCancelled:
+		f.Outcome = p.Outcome
+	default:
+		v.add("outcome", fmt.Sprintf("must be %s, %s or %s", model.AuditSuccess, model.AuditFailure, model.AuditCancelled))
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && !f.Since.Before(f.Until) {
+		v.add("since", "must be before until")
+	}
+	if p.Cursor != "" {
+		id, ok := decodeCursor(auditCursorSort, p.Cursor)
+		if !ok {
+			v.add("cursor", "is invalid")
+		}
+		f.BeforeID = id
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	// One extra row tells whether there is a next page.
+	limit := f.Limit
+	f.Limit++
+	entries, err := s.repo.List(f)
+	if err != nil {
+		return nil, err
+	}
+	page := &AuditPage{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.NextCursor = encodeCursor(auditCursorSort, page.Entries[limit-1].ID)
+	}
+	return page, nil
+}
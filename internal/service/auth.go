@@ -0,0 +1,106 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"myproject/internal/model"
+	"myproject/internal/repository"
+)
+
+var (
+	ErrInvalidToken  = errors.New("invalid or revoked API token")
+	ErrTokenNotFound = errors.New("API token not found")
+)
+
+// tokenPrefix marks myapp tokens so they are easy to spot in configs and leaks.
+const tokenPrefix = "myapp_"
+
+// roleRank orders the roles; a role may do everything a lower one may.
+var roleRank = map[string]int{
+	model.RoleViewer:   1,
+	model.RoleOperator: 2,
+	model.RoleAdmin:    3,
+}
+
+// ValidRole reports whether role is one of the model.Role* values.
+func ValidRole(role string) bool {
+	return roleRank[role] > 0
+}
+
+// RoleAllows reports whether a token with role may call an endpoint that requires required.
+func RoleAllows(role, required string) bool {
+	return ValidRole(role) && roleRank[role] >= roleRank[required]
+}
+
+// AuthService issues, checks and revokes API tokens.
+type AuthService struct {
+	repo *repository.TokenRepo
+}
+
+// NewAuthService creates a new AuthService.
+func NewAuthService(repo *repository.TokenRepo) *AuthService {
+	return &AuthService{repo: repo}
+}
+
+// hashToken is the stored form of a token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue creates a token for name with role. The returned token string is the
+// only copy; just its hash is stored.
+func (s *AuthService) Issue(name, role string) (string, *model.APIToken, error) {
+	name = strings.TrimSpace(name)
+	var v validator
+	v.text("name", name, true)
+	if !ValidRole(role) {
+		v.add("role", fmt.Sprintf("must be %s, %s or %s", model.RoleViewer, model.RoleOperator, model.RoleAdmin))
+	}
+	if err := v.err(); err != nil {
+		return "", nil, err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	t, err := s.repo.Create(name, role, hashToken(token))
+	if err != nil {
+		return "", nil, err
+	}
+	return token, t, nil
+}
+
+// Authenticate returns the active token for a bearer token string, or
+// ErrInvalidToken when it is unknown or revoked.
+func (s *AuthService) Authenticate(token string) (*model.APIToken, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return nil, ErrInvalidToken
+	}
+	t, err := s.repo.GetActiveByHash(hashToken(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+	return t, err
+}
+
+// List returns all tokens, revoked ones included.
+func (s *AuthService) List() ([]*model.APIToken, error) {
+	return s.repo.List()
+}
+
+// Revoke revokes token id; ErrTokenNotFound if it does not exist or is already revoked.
+func (s *AuthService) Revoke(id int64) error {
+	err := s.repo.Revoke(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrTokenNotFound
+	}
+	return err
+}
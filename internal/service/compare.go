@@ -0,0 +1,145 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"myproject/internal/model"
+)
+
+// ServerCoverage is what one remote server has for a stream compared to the local server.
+type ServerCoverage struct {
+	ServerID        int            `json:"server_id"`
+	InImportOrder   bool           `json:"in_import_order"`
+	RecordedMinutes float64        `json:"recorded_minutes"`
+	CoveragePercent float64        `json:"coverage_percent"`
+	FillMinutes     float64        `json:"fill_minutes"`
+	Fills           []model.Period `json:"fills"`
+	UniqueMinutes   float64        `json:"unique_minutes"`
+	Error           string         `json:"error,omitempty"`
+}
+
+// StreamComparison compares the local coverage of a stream with every remote server.
+// Fills follow the stream's import order: each server fills what the
+// servers before it could not. Unique minutes are covered by that server only.
+type StreamComparison struct {
+	StreamID           int              `json:"stream_id"`
+	StreamName         string           `json:"stream_name,omitempty"`
+	Local              StreamCoverage   `json:"local"`
+	LocalUniqueMinutes float64          `json:"local_unique_minutes"`
+	Servers            []ServerCoverage `json:"servers"`
+	UnfilledMinutes    float64          `json:"unfilled_minutes"`
+	Unfilled           []model.Period   `json:"unfilled"`
+}
+
+// RemoteServers returns every remote server ID named in serversOrder, the
+// import order configuration returned by GetServersOrder. It is the only
+// server registry the sync service has.
+func (s *SyncService) RemoteServers(serversOrder map[int][]int) []int {
+	local := s.LocalServerID()
+	seen := make(map[int]bool)
+	var ids []int
+	for _, order := range serversOrder {
+		for _, id := range order {
+			if id != local && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Compare computes coverage for [start, end) on the local DB and on every
+// remote server, and works out per stream which server would fill which gaps.
+func (s *SyncService) Compare(start, end time.Time, streamType string, streamID int) ([]StreamComparison, error) {
+	local, err := s.Coverage(s.LocalDB, start, end, streamType, streamID)
+	if err != nil {
+		return nil, fmt.Errorf("local: %w", err)
+	}
+	serversOrder := s.GetServersOrder(streamType)
+	servers := s.RemoteServers(serversOrder)
+
+	remote := make(map[int]map[int]StreamCoverage)
+	remoteErr := make(map[int]string)
+	for _, id := range servers {
+		d := s.GetRemoteDB(id)
+		if d == nil {
+			remoteErr[id] = "no DB"
+			continue
+		}
+		cov, err := s.Coverage(d, start, end, streamType, streamID)
+		if err != nil {
+			remoteErr[id] = err.Error()
+			continue
+		}
+		byStream := make(map[int]StreamCoverage, len(cov))
+		for _, c := range cov {
+			byStream[c.StreamID] = c
+		}
+		remote[id] = byStream
+	}
+
+	result := make([]StreamComparison, 0, len(local))
+	for _, lc := range local {
+		cmp := StreamComparison{StreamID: lc.StreamID, StreamName: lc.StreamName, Local: lc}
+
+		// Import order first, then the other known servers.
+		order := append([]int(nil), serversOrder[lc.StreamID]...)
+		inOrder := make(map[int]bool)
+		for _, id := range order {
+			inOrder[id] = true
+		}
+		for _, id := range servers {
+			if !inOrder[id] {
+				order = append(order, id)
+			}
+		}
+
+		remaining := lc.Gaps
+		all := map[int][]model.Period{-1: lc.Recorded}
+		for _, id := range order {
+			sc := ServerCoverage{ServerID: id, InImportOrder: inOrder[id]}
+			if e, ok := remoteErr[id]; ok {
+				sc.Error = e
+				cmp.Servers = append(cmp.Servers, sc)
+				continue
+			}
+			rc := remote[id][lc.StreamID]
+			all[id] = rc.Recorded
+			sc.RecordedMinutes = rc.RecordedMinutes
+			sc.CoveragePercent = rc.CoveragePercent
+			if sc.InImportOrder {
+				sc.Fills = intersectPeriods(remaining, rc.Recorded)
+				sc.FillMinutes = mathRound(periodsDuration(sc.Fills).Minutes(), 2)
+				remaining = subtractPeriods(remaining, sc.Fills)
+			}
+			cmp.Servers = append(cmp.Servers, sc)
+		}
+		cmp.Unfilled = remaining
+		cmp.UnfilledMinutes = mathRound(periodsDuration(remaining).Minutes(), 2)
+
+		cmp.LocalUniqueMinutes = uniqueMinutes(-1, all)
+		for i := range cmp.Servers {
+			if cmp.Servers[i].Error == "" {
+				cmp.Servers[i].UniqueMinutes = uniqueMinutes(cmp.Servers[i].ServerID, all)
+			}
+		}
+		result = append(result, cmp)
+	}
+	return result, nil
+}
+
+// uniqueMinutes returns the minutes recorded by server id and by no other server in all.
+func uniqueMinutes(id int, all map[int][]model.Period) float64 {
+	var others []model.Period
+	for other, rec := range all {
+		if other != id {
+			others = append(others, rec...)
+		}
+	}
+	unique := subtractPeriods(all[id], mergePeriods(others))
+	return mathRound(periodsDuration(unique).Minutes(), 2)
+}
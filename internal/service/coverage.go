@@ -0,0 +1,61 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"myproject/internal/model"
+	"myproject/internal/repository"
+)
+
+// StreamCoverage is the recording coverage of one stream in a time window.
+type StreamCoverage struct {
+	StreamID        int            `json:"stream_id"`
+	StreamName      string         `json:"stream_name,omitempty"`
+	RecordedMinutes float64        `json:"recorded_minutes"`
+	GapMinutes      float64        `json:"gap_minutes"`
+	CoveragePercent float64        `json:"coverage_percent"`
+	Recorded        []model.Period `json:"-"`
+	Gaps            []model.Period `json:"gaps"`
+}
+
+// Coverage computes recorded and non-recorded periods per stream on d for
+// [start, end), the same way StartRecordProcessing finds the gaps to fill.
+// A negative streamID means every enabled stream of streamType.
+func (s *SyncService) Coverage(d repository.DB, start, end time.Time, streamType string, streamID int) ([]StreamCoverage, error) {
+	if d == nil {
+		return nil, fmt.Errorf("no DB")
+	}
+	if !start.Before(end) {
+		return nil, fmt.Errorf("start must be before end")
+	}
+	var recorded, nonRecorded map[int][]model.Period
+	if dbErrors := s.countDBErrors(func() {
+		recorded, nonRecorded = s.getRecordingStatusInPeriod(d, start, end, streamType, streamID)
+	}); dbErrors > 0 {
+		return nil, fmt.Errorf("%d DB errors while computing coverage", dbErrors)
+	}
+
+	window := end.Sub(start)
+	result := make([]StreamCoverage, 0, len(recorded))
+	for streamID, rec := range recorded {
+		rec = mergePeriods(clipPeriods(rec, start, end))
+		gaps := mergePeriods(clipPeriods(nonRecorded[streamID], start, end))
+		c := StreamCoverage{
+			StreamID:        streamID,
+			StreamName:      s.Ut.GetStreamNameByID(s.LocalDB, streamID),
+			RecordedMinutes: mathRound(periodsDuration(rec).Minutes(), 2),
+			GapMinutes:      mathRound(periodsDuration(gaps).Minutes(), 2),
+			CoveragePercent: mathRound(100*periodsDuration(rec).Seconds()/window.Seconds(), 2),
+			Recorded:        rec,
+			Gaps:            gaps,
+		}
+		for i := range c.Gaps {
+			c.Gaps[i].StreamID = 0
+		}
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].StreamID < result[j].StreamID })
+	return result, nil
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// encodeCursor makes the opaque cursor of the page after id. tag names what
+// the cursor pages through (a user sort, the audit log), so a cursor of one
+// list is rejected by another.
+func encodeCursor(tag string, id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(tag + ":" + strconv.FormatInt(id, 10)))
+}
+
+// decodeCursor returns the id of a cursor made for tag.
+func decodeCursor(tag, cursor string) (int64, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, false
+	}
+	prefix, idStr, ok := strings.Cut(string(raw), ":")
+	if !ok || prefix != tag {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	return id, err == nil && id > 0
+}
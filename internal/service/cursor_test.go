@@ -0,0 +1,70 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"myproject/internal/repository"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		tag string
+		id  int64
+	}{
+		{"id", 1},
+		{"-id", 42},
+		{auditCursorSort, 1 << 40},
+	} {
+		c := encodeCursor(tt.tag, tt.id)
+		if id, ok := decodeCursor(tt.tag, c); !ok || id != tt.id {
+			t.Errorf("decodeCursor(%q, encodeCursor(%q, %d)) = %d, %v", tt.tag, tt.tag, tt.id, id, ok)
+		}
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name   string
+		tag    string
+		cursor string
+	}{
+		{"other sort", "-id", encodeCursor("id", 5)},
+		{"users cursor on the audit log", auditCursorSort, encodeCursor("id", 5)},
+		{"audit cursor on users", "id", encodeCursor(auditCursorSort, 5)},
+		{"not base64", "id", "not a cursor!"},
+		{"padded base64", "id", base64.URLEncoding.EncodeToString([]byte("id:5"))},
+		{"truncated", "id", encodeCursor("id", 12345)[:5]},
+		{"no separator", "id", raw("id5")},
+		{"no id", "id", raw("id:")},
+		{"id not a number", "id", raw("id:5x")},
+		{"zero id", "id", raw("id:0")},
+		{"negative id", "id", raw("id:-5")},
+		{"tag prefix only", "id", raw("i:5")},
+		{"empty", "id", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if id, ok := decodeCursor(tt.tag, tt.cursor); ok {
+				t.Errorf("decodeCursor(%q, %q) = %d, want rejected", tt.tag, tt.cursor, id)
+			}
+		})
+	}
+}
+
+func TestListRejectsCursorOfOtherSort(t *testing.T) {
+	users := NewUserService(repository.NewUserRepo(nil))
+	_, err := users.List(ListUsersParams{Sort: "-id", Cursor: encodeCursor("id", 5)})
+	var ve *ValidationError
+	if !errors.As(err, &ve) || len(ve.Fields) != 1 || ve.Fields[0].Field != "cursor" {
+		t.Errorf("users: err = %v, want a cursor field error", err)
+	}
+
+	audit := NewAuditService(repository.NewAuditRepo(nil))
+	_, err = audit.List(ListAuditParams{Cursor: encodeCursor("id", 5)})
+	if !errors.As(err, &ve) || len(ve.Fields) != 1 || ve.Fields[0].Field != "cursor" {
+		t.Errorf("audit: err = %v, want a cursor field error", err)
+	}
+}
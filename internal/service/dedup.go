@@ -0,0 +1,232 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"myproject/internal/model"
+)
+
+// Dedup scoring strategies: which record of a cluster is kept.
+const (
+	// DedupByRate keeps the record with the most recorded time weighted by
+	// record rate, the measure SyncRecordsFromOtherServers compares with.
+	DedupByRate = "rate"
+	// DedupByDuration keeps the longest record.
+	DedupByDuration = "duration"
+	// DedupPreferLocal keeps a record recorded on the local server if there
+	// is one, then falls back to DedupByRate.
+	DedupPreferLocal = "local"
+)
+
+// DedupRecord is one record of a duplicate cluster with its score.
+type DedupRecord struct {
+	RecordID         int       `json:"record_id"`
+	Path             string    `json:"path"`
+	Start            time.Time `json:"start"`
+	End              time.Time `json:"end"`
+	RecordRate       float64   `json:"record_rate"`
+	ImportedSourceID int       `json:"imported_source_id,omitempty"`
+	Score            float64   `json:"score"`
+}
+
+// DedupCluster is a set of overlapping approved records of one stream.
+type DedupCluster struct {
+	StreamID int           `json:"stream_id"`
+	Keep     DedupRecord   `json:"keep"`
+	Disable  []DedupRecord `json:"disable"`
+}
+
+// ValidDedupStrategy reports whether strategy is known to FindDuplicates.
+func ValidDedupStrategy(strategy string) bool {
+	switch strategy {
+	case DedupByRate, DedupByDuration, DedupPreferLocal:
+		return true
+	}
+	return false
+}
+
+func dedupScore(r model.Record, strategy string, serverLocalID int) float64 {
+	seconds := r.EndedAt.Sub(r.StartedAt).Seconds()
+	switch strategy {
+	case DedupByDuration:
+		return mathRound(seconds, 2)
+	case DedupPreferLocal:
+		score := seconds * r.RecordRate
+		if r.ImportedSourceID <= 0 || r.ImportedSourceID == serverLocalID {
+			// Above any rate score: a record is at most an hour long.
+			score += 1e6
+		}
+		return mathRound(score, 2)
+	default:
+		return mathRound(seconds*r.RecordRate, 2)
+	}
+}
+
+// overlapsBeyond reports whether a and b overlap by at least minOverlap of the shorter one.
+func overlapsBeyond(a, b model.Record, minOverlap float64) bool {
+	overlap := minTime(a.EndedAt, b.EndedAt).Sub(maxTime(a.StartedAt, b.StartedAt))
+	if overlap <= 0 {
+		return false
+	}
+	shorter := a.EndedAt.Sub(a.StartedAt)
+	if d := b.EndedAt.Sub(b.StartedAt); d < shorter {
+		shorter = d
+	}
+	if shorter <= 0 {
+		return true
+	}
+	return overlap.Seconds()/shorter.Seconds() >= minOverlap
+}
+
+// FindDuplicates groups the approved local records started between start and
+// end into clusters of records of the same stream that overlap by at least
+// minOverlap (0-1] of the shorter record. The best record of each cluster by
+// strategy is kept; only records overlapping it that much are listed to be
+// disabled.
+func (s *SyncService) FindDuplicates(start, end time.Time, streamType string, streamID int, minOverlap float64, strategy string) ([]DedupCluster, error) {
+	if s.LocalDB == nil {
+		return nil, fmt.Errorf("no local DB")
+	}
+	if !ValidDedupStrategy(strategy) {
+		return nil, fmt.Errorf("unknown strategy %q", strategy)
+	}
+	if minOverlap <= 0 || minOverlap > 1 {
+		return nil, fmt.Errorf("min overlap must be in (0, 1]")
+	}
+	sqlStreamID := ""
+	if streamID >= 0 {
+		sqlStreamID = fmt.Sprintf(" and stream_id=%d ", streamID)
+	}
+	records, err := s.LocalDB.SelectRecords(fmt.Sprintf(`
+select * from records
+where started_at >= '%s' and started_at < '%s'
+  %s
+  %s
+  and is_record_approved = true
+  and is_deleted = false
+order by stream_id, started_at
+`, start.Format("2006-01-02 15:04:05"), end.Format("2006-01-02 15:04:05"), sqlStreamID, s.getStreamTypeSQL(streamType)))
+	if err != nil {
+		return nil, fmt.Errorf("select records: %w", err)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].StreamID != records[j].StreamID {
+			return records[i].StreamID < records[j].StreamID
+		}
+		return records[i].StartedAt.Before(records[j].StartedAt)
+	})
+
+	serverLocalID := s.LocalServerID()
+	var clusters []DedupCluster
+	for _, group := range groupOverlapping(records, minOverlap) {
+		clusters = append(clusters, s.dedupGroup(group, strategy, serverLocalID, minOverlap)...)
+	}
+	return clusters, nil
+}
+
+// groupOverlapping splits records, sorted by stream and start, into groups of
+// records connected by overlaps of at least minOverlap. Groups are transitive
+// (A-B-C when only neighbours overlap); dedupGroup resolves them.
+func groupOverlapping(records []model.Record, minOverlap float64) [][]model.Record {
+	var groups [][]model.Record
+	var group []model.Record
+	var groupEnd time.Time
+	flush := func() {
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+		group = nil
+	}
+	for _, r := range records {
+		joins := false
+		if len(group) > 0 && group[0].StreamID == r.StreamID && r.StartedAt.Before(groupEnd) {
+			for _, g := range group {
+				if overlapsBeyond(g, r, minOverlap) {
+					joins = true
+					break
+				}
+			}
+		}
+		if !joins {
+			flush()
+			groupEnd = r.EndedAt
+		}
+		group = append(group, r)
+		groupEnd = maxTime(groupEnd, r.EndedAt)
+	}
+	flush()
+	return groups
+}
+
+func dedupRecord(r model.Record, strategy string, serverLocalID int) DedupRecord {
+	return DedupRecord{
+		RecordID:         r.ID,
+		Path:             r.Path,
+		Start:            r.StartedAt,
+		End:              r.EndedAt,
+		RecordRate:       r.RecordRate,
+		ImportedSourceID: r.ImportedSourceID,
+		Score:            dedupScore(r, strategy, serverLocalID),
+	}
+}
+
+// dedupGroup keeps the best record of group and disables only the records that
+// overlap it by at least minOverlap; the others hold coverage the kept record
+// does not have, so they are grouped again among themselves.
+func (s *SyncService) dedupGroup(group []model.Record, strategy string, serverLocalID int, minOverlap float64) []DedupCluster {
+	best := 0
+	for i := 1; i < len(group); i++ {
+		si, sb := dedupScore(group[i], strategy, serverLocalID), dedupScore(group[best], strategy, serverLocalID)
+		if si > sb || (si == sb && group[i].ID < group[best].ID) {
+			best = i
+		}
+	}
+	c := DedupCluster{StreamID: group[best].StreamID, Keep: dedupRecord(group[best], strategy, serverLocalID)}
+	var rest []model.Record
+	for i, r := range group {
+		switch {
+		case i == best:
+		case overlapsBeyond(group[best], r, minOverlap):
+			c.Disable = append(c.Disable, dedupRecord(r, strategy, serverLocalID))
+		default:
+			rest = append(rest, r)
+		}
+	}
+	sort.SliceStable(c.Disable, func(i, j int) bool {
+		if c.Disable[i].Score != c.Disable[j].Score {
+			return c.Disable[i].Score > c.Disable[j].Score
+		}
+		return c.Disable[i].RecordID < c.Disable[j].RecordID
+	})
+	var clusters []DedupCluster
+	if len(c.Disable) > 0 {
+		clusters = append(clusters, c)
+	}
+	for _, g := range groupOverlapping(rest, minOverlap) {
+		clusters = append(clusters, s.dedupGroup(g, strategy, serverLocalID, minOverlap)...)
+	}
+	return clusters
+}
+
+// DisableDuplicates marks the records to disable of clusters not approved and
+// disables their results. It returns the number of records disabled.
+func (s *SyncService) DisableDuplicates(clusters []DedupCluster) (int, error) {
+	if s.LocalDB == nil {
+		return 0, fmt.Errorf("no local DB")
+	}
+	var ids []int
+	for _, c := range clusters {
+		for _, r := range c.Disable {
+			ids = append(ids, r.RecordID)
+		}
+	}
+	if err := s.updateRecordNotApproved(s.LocalDB, ids); err != nil {
+		return 0, fmt.Errorf("update records: %w", err)
+	}
+	if err := s.disableResults(s.LocalDB, ids); err != nil {
+		return 0, fmt.Errorf("disable results: %w", err)
+	}
+	return len(ids), nil
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"myproject/internal/model"
+)
+
+// dedupRec is an approved record of stream 1 from "HH:MM" to "HH:MM" on 2025-01-01.
+func dedupRec(id int, start, end string, rate float64, importedFrom int) model.Record {
+	at := func(s string) time.Time {
+		t, err := time.Parse("2006-01-02 15:04", "2025-01-01 "+s)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+	return model.Record{ID: id, StreamID: 1, StartedAt: at(start), EndedAt: at(end), RecordRate: rate,
+		ImportedSourceID: importedFrom, IsRecordApproved: true}
+}
+
+func recordIDs(records []model.Record) []int {
+	ids := make([]int, len(records))
+	for i, r := range records {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func TestGroupOverlapping(t *testing.T) {
+	other := dedupRec(4, "10:00", "10:20", 1, 0)
+	other.StreamID = 2
+	tests := []struct {
+		name       string
+		records    []model.Record
+		minOverlap float64
+		want       [][]int
+	}{
+		{
+			name:       "A-B-C chain is one group",
+			records:    []model.Record{dedupRec(1, "10:00", "10:20", 1, 0), dedupRec(2, "10:15", "10:35", 1, 0), dedupRec(3, "10:30", "10:50", 1, 0)},
+			minOverlap: 0.2,
+			want:       [][]int{{1, 2, 3}},
+		},
+		{
+			name:       "chain below min overlap",
+			records:    []model.Record{dedupRec(1, "10:00", "10:20", 1, 0), dedupRec(2, "10:15", "10:35", 1, 0), dedupRec(3, "10:30", "10:50", 1, 0)},
+			minOverlap: 0.5,
+			want:       nil,
+		},
+		{
+			name:       "joins through any member, not only the last",
+			records:    []model.Record{dedupRec(1, "10:00", "10:40", 1, 0), dedupRec(2, "10:05", "10:10", 1, 0), dedupRec(3, "10:20", "10:30", 1, 0)},
+			minOverlap: 0.5,
+			want:       [][]int{{1, 2, 3}},
+		},
+		{
+			name:       "disjoint records",
+			records:    []model.Record{dedupRec(1, "10:00", "10:20", 1, 0), dedupRec(2, "10:20", "10:40", 1, 0)},
+			minOverlap: 0.1,
+			want:       nil,
+		},
+		{
+			name:       "other stream starts a new group",
+			records:    []model.Record{dedupRec(1, "10:00", "10:20", 1, 0), dedupRec(2, "10:00", "10:20", 1, 0), other},
+			minOverlap: 0.5,
+			want:       [][]int{{1, 2}},
+		},
+		{
+			name: "two groups",
+			records: []model.Record{dedupRec(1, "10:00", "10:20", 1, 0), dedupRec(2, "10:05", "10:20", 1, 0),
+				dedupRec(3, "11:00", "11:20", 1, 0), dedupRec(5, "11:00", "11:10", 1, 0)},
+			minOverlap: 0.5,
+			want:       [][]int{{1, 2}, {3, 5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]int
+			for _, g := range groupOverlapping(tt.records, tt.minOverlap) {
+				got = append(got, recordIDs(g))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groups %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedupGroup(t *testing.T) {
+	const serverLocalID = 1
+	// 3 is the best by rate, 2 by duration, 1 is the only local record.
+	strategies := []model.Record{
+		dedupRec(1, "10:05", "10:20", 0.4, 0),
+		dedupRec(2, "10:00", "10:30", 0.5, 3),
+		dedupRec(3, "10:00", "10:20", 1, 3),
+	}
+	type cluster struct {
+		keep    int
+		disable []int
+	}
+	tests := []struct {
+		name     string
+		group    []model.Record
+		strategy string
+		want     []cluster
+	}{
+		{
+			name:     "rate",
+			group:    strategies,
+			strategy: DedupByRate,
+			want:     []cluster{{keep: 3, disable: []int{2, 1}}},
+		},
+		{
+			name:     "duration",
+			group:    strategies,
+			strategy: DedupByDuration,
+			want:     []cluster{{keep: 2, disable: []int{3, 1}}},
+		},
+		{
+			name:     "local",
+			group:    strategies,
+			strategy: DedupPreferLocal,
+			want:     []cluster{{keep: 1, disable: []int{3, 2}}},
+		},
+		{
+			name:     "tie keeps the lowest ID",
+			group:    []model.Record{dedupRec(7, "10:00", "10:20", 1, 0), dedupRec(5, "10:00", "10:20", 1, 0), dedupRec(6, "10:00", "10:20", 1, 0)},
+			strategy: DedupByRate,
+			want:     []cluster{{keep: 5, disable: []int{6, 7}}},
+		},
+		{
+			name:     "A-B-C chain kept in the middle disables both ends",
+			group:    []model.Record{dedupRec(1, "10:00", "10:20", 0.5, 0), dedupRec(2, "10:15", "10:35", 1, 0), dedupRec(3, "10:30", "10:50", 0.5, 0)},
+			strategy: DedupByRate,
+			want:     []cluster{{keep: 2, disable: []int{1, 3}}},
+		},
+		{
+			name:     "A-B-C chain kept at one end leaves the other end",
+			group:    []model.Record{dedupRec(1, "10:00", "10:20", 1, 0), dedupRec(2, "10:15", "10:35", 0.5, 0), dedupRec(3, "10:30", "10:50", 0.5, 0)},
+			strategy: DedupByRate,
+			want:     []cluster{{keep: 1, disable: []int{2}}},
+		},
+		{
+			name: "records outside the kept one are deduplicated among themselves",
+			group: []model.Record{dedupRec(1, "10:00", "10:20", 1, 0), dedupRec(2, "10:15", "10:35", 0.5, 0),
+				dedupRec(3, "10:30", "10:50", 0.8, 0), dedupRec(4, "10:30", "10:50", 0.6, 0)},
+			strategy: DedupByRate,
+			want:     []cluster{{keep: 1, disable: []int{2}}, {keep: 3, disable: []int{4}}},
+		},
+	}
+	s := &SyncService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []cluster
+			for _, c := range s.dedupGroup(tt.group, tt.strategy, serverLocalID, 0.2) {
+				g := cluster{keep: c.Keep.RecordID}
+				for _, r := range c.Disable {
+					g.disable = append(g.disable, r.RecordID)
+				}
+				got = append(got, g)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clusters %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindDuplicatesNoDB(t *testing.T) {
+	s := &SyncService{}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := s.FindDuplicates(start, start.Add(time.Hour), "audio", -1, 0.5, DedupByRate); err == nil {
+		t.Error("FindDuplicates without a local DB succeeded")
+	}
+}
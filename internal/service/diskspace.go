@@ -0,0 +1,64 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// filesSize returns the total size of the regular files matching pattern.
+func filesSize(pattern string) int64 {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return 0
+	}
+	var total int64
+	for _, m := range matches {
+		fi, err := os.Stat(m)
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		total += fi.Size()
+	}
+	return total
+}
+
+// checkFreeSpace reports whether dir keeps at least the run threshold free
+// after needed more bytes are written. Once the check fails the run is marked
+// out of space and stays that way. When the free space can't be read the copy
+// is allowed (fail open): the guard protects against filling the disk, and a
+// statfs error on a disk that takes the writes must not stop the sync. The
+// error is logged for every such copy.
+func (s *SyncService) checkFreeSpace(dir string, needed int64) bool {
+	rs := s.run.Load()
+	if rs.report.OutOfSpace {
+		return false
+	}
+	free, err := freeSpace(dir)
+	if err != nil {
+		fmt.Printf("  > free space check error on %s, copy allowed: %v\n", dir, err)
+		return true
+	}
+	if free-needed < rs.minFreeBytes {
+		fmt.Printf("  > NOT ENOUGH DISK SPACE on %s: free = %d bytes, needed = %d bytes, threshold = %d bytes\n",
+			dir, free, needed, rs.minFreeBytes)
+		rs.update(func(r *RunReport) { r.OutOfSpace = true })
+		return false
+	}
+	return true
+}
+
+// isOutOfSpace reports whether copying needed more bytes must be refused.
+// After the first refusal nothing more is copied; the refused copies of the
+// rest of the run add up to the bytes the remaining plan needs.
+func (s *SyncService) isOutOfSpace(needed int64, dstRoot string) bool {
+	rs := s.run.Load()
+	if rs == nil || rs.minFreeBytes <= 0 {
+		return false
+	}
+	if s.checkFreeSpace(dstRoot, needed) {
+		return false
+	}
+	rs.update(func(r *RunReport) { r.BytesNeeded += needed })
+	return true
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published on a SyncService event bus.
+const (
+	EventItemStarted = "item_started"
+	EventItemDecided = "item_decided"
+	EventItemCopied  = "item_copied"
+	EventProgress    = "progress"
+	EventRunFinished = "run_finished"
+)
+
+// Event is a progress event of a sync run.
+type Event struct {
+	Seq      int64      `json:"seq"`
+	Type     string     `json:"type"`
+	Time     time.Time  `json:"time"`
+	Item     int        `json:"item,omitempty"`
+	Total    int        `json:"total,omitempty"`
+	StreamID int        `json:"stream_id,omitempty"`
+	RecordID int        `json:"record_id,omitempty"`
+	ServerID int        `json:"server_id,omitempty"`
+	Start    *time.Time `json:"start,omitempty"`
+	End      *time.Time `json:"end,omitempty"`
+	Path     string     `json:"path,omitempty"`
+	Status   string     `json:"status,omitempty"`
+	Percent  float64    `json:"percent,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+const (
+	// eventReplaySize is how many past events a new subscriber can catch up on.
+	eventReplaySize = 256
+	// subscriberBuffer is the channel size per subscriber; slow subscribers lose events.
+	subscriberBuffer = 64
+)
+
+// EventBus fans out events to subscribers without ever blocking the publisher.
+type EventBus struct {
+	mu     sync.Mutex
+	seq    int64
+	replay []Event
+	subs   map[chan Event]struct{}
+	closed bool
+}
+
+// NewEventBus creates an empty EventBus.
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[chan Event]struct{})}
+}
+
+// Publish stamps e with a sequence number and time and delivers it to every subscriber.
+func (b *EventBus) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.seq++
+	e.Seq = b.seq
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.replay = append(b.replay, e)
+	if len(b.replay) > eventReplaySize {
+		b.replay = b.replay[len(b.replay)-eventReplaySize:]
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel with the buffered events after afterSeq followed
+// by live events, and a function to unsubscribe. The channel is closed when
+// the bus is closed or on unsubscribe.
+func (b *EventBus) Subscribe(afterSeq int64) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var backlog []Event
+	for _, e := range b.replay {
+		if e.Seq > afterSeq {
+			backlog = append(backlog, e)
+		}
+	}
+	ch := make(chan Event, len(backlog)+subscriberBuffer)
+	for _, e := range backlog {
+		ch <- e
+	}
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	b.subs[ch] = struct{}{}
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close ends all subscriptions; later events are dropped.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// publish sends e on the service event bus, if any.
+func (s *SyncService) publish(e Event) {
+	if s.Events != nil {
+		s.Events.Publish(e)
+	}
+}
+
+// updateProgress stores the task completion percentage and publishes it.
+func (s *SyncService) updateProgress(taskID int, percent float64) {
+	s.Ut.UpdateCompletionPercentage(s.LocalDB, taskID, percent)
+	s.publish(Event{Type: EventProgress, Percent: percent})
+}
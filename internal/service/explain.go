@@ -0,0 +1,369 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"myproject/internal/model"
+)
+
+// Kinds of sync items an Explanation can be about.
+const (
+	ExplainProblemRecord = "problem_record"
+	ExplainGap           = "gap"
+	ExplainShortGap      = "short_gap"
+	ExplainCovered       = "covered"
+)
+
+// ExplainCandidate is a remote record overlapping the explained item.
+type ExplainCandidate struct {
+	ServerID   int       `json:"server_id"`
+	RecordID   int       `json:"record_id"`
+	Path       string    `json:"path"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Duration   float64   `json:"duration"`
+	RecordRate float64   `json:"record_rate"`
+	PeriodRate float64   `json:"period_rate"`
+	// CompareRate is set for the best candidate of each server (record_rate × period_rate).
+	CompareRate *float64 `json:"compare_rate,omitempty"`
+	// Excluded lists the query filters of SyncRecordsFromOtherServers the record fails.
+	Excluded []string `json:"excluded,omitempty"`
+	// ExcludedAddMode lists the filters of AddRecordsFromOtherServers, when add mode ran.
+	ExcludedAddMode []string `json:"excluded_add_mode,omitempty"`
+	Returned        bool     `json:"returned"`
+	ReturnedAddMode bool     `json:"returned_add_mode,omitempty"`
+	Best            bool     `json:"best"`
+	Selected        bool     `json:"selected"`
+}
+
+// ExplainServer is one server of the stream's import order.
+type ExplainServer struct {
+	ServerID        int    `json:"server_id"`
+	Position        int    `json:"position"`
+	Returned        int    `json:"returned"`
+	ReturnedAddMode int    `json:"returned_add_mode,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// Explanation is the sync decision for the item of a stream at a given time.
+type Explanation struct {
+	StreamID    int                `json:"stream_id"`
+	StreamName  string             `json:"stream_name,omitempty"`
+	StreamType  string             `json:"stream_type"`
+	At          time.Time          `json:"at"`
+	Kind        string             `json:"kind"`
+	Start       time.Time          `json:"start"`
+	End         time.Time          `json:"end"`
+	RecordID    int                `json:"record_id,omitempty"`
+	AddMode     bool               `json:"add_mode"`
+	Servers     []ExplainServer    `json:"servers"`
+	Candidates  []ExplainCandidate `json:"candidates"`
+	Steps       []string           `json:"steps"`
+	Status      string             `json:"status"`
+	WindowStart time.Time          `json:"window_start"`
+	WindowEnd   time.Time          `json:"window_end"`
+}
+
+// explainTrace collects what the selection functions saw while explaining one item.
+type explainTrace struct {
+	addMode  bool
+	servers  map[int]*ExplainServer
+	returned map[[2]int]bool
+	addRet   map[[2]int]bool
+	best     map[[2]int]bool
+	selected map[[2]int]bool
+	compare  map[[2]int]float64
+	steps    []string
+}
+
+func newExplainTrace() *explainTrace {
+	return &explainTrace{
+		servers:  make(map[int]*ExplainServer),
+		returned: make(map[[2]int]bool),
+		addRet:   make(map[[2]int]bool),
+		best:     make(map[[2]int]bool),
+		selected: make(map[[2]int]bool),
+		compare:  make(map[[2]int]float64),
+	}
+}
+
+func (t *explainTrace) server(id int) *ExplainServer {
+	sv, ok := t.servers[id]
+	if !ok {
+		sv = &ExplainServer{ServerID: id}
+		t.servers[id] = sv
+	}
+	return sv
+}
+
+// The trace hooks below are no-ops outside Explain.
+
+func (s *SyncService) traceStep(format string, args ...interface{}) {
+	if s.trace != nil {
+		s.trace.steps = append(s.trace.steps, fmt.Sprintf(format, args...))
+	}
+}
+
+func (s *SyncService) traceServer(serverID int, recs []model.Record) {
+	if s.trace == nil {
+		return
+	}
+	sv := s.trace.server(serverID)
+	for _, r := range recs {
+		if s.trace.addMode {
+			sv.ReturnedAddMode++
+			s.trace.addRet[[2]int{serverID, r.ID}] = true
+		} else {
+			sv.Returned++
+			s.trace.returned[[2]int{serverID, r.ID}] = true
+		}
+	}
+}
+
+func (s *SyncService) traceServerError(serverID int, msg string) {
+	if s.trace != nil {
+		s.trace.server(serverID).Error = msg
+		s.traceStep("server %d skipped: %s", serverID, msg)
+	}
+}
+
+func (s *SyncService) traceBest(serverID, recordID int) {
+	if s.trace != nil {
+		s.trace.best[[2]int{serverID, recordID}] = true
+	}
+}
+
+func (s *SyncService) traceCompare(serverID, recordID int, compareRate float64) {
+	if s.trace != nil {
+		s.trace.compare[[2]int{serverID, recordID}] = compareRate
+	}
+}
+
+func (s *SyncService) traceSelected(serverID, recordID int) {
+	if s.trace != nil {
+		s.trace.selected[[2]int{serverID, recordID}] = true
+	}
+}
+
+// streamTypeName maps streams.stream_type to the CLI stream type.
+func streamTypeName(t int) string {
+	switch t {
+	case 1:
+		return "audio"
+	case 2:
+		return "video"
+	default:
+		return ""
+	}
+}
+
+// Explain finds the sync item of streamID at time at and runs the selection of
+// SyncRecordsFromOtherServers (and AddRecordsFromOtherServers when addMode is
+// set) for it in dry-run mode, recording every candidate per server and why
+// it was or was not chosen. The sync window is the day containing at, so the
+// items are the same as in a sync run over that day. Nothing is copied or
+// written to a DB.
+func (s *SyncService) Explain(streamID int, at time.Time, addMode bool) (*Explanation, error) {
+	if s.LocalDB == nil {
+		return nil, fmt.Errorf("no local DB")
+	}
+	// The selection functions keep their run state and trace on the service:
+	// explain on a copy with the same dependencies so a run of s is untouched.
+	x := &SyncService{LocalDB: s.LocalDB, GetRemoteDB: s.GetRemoteDB, Ut: s.Ut, Paths: s.Paths}
+	return x.explain(streamID, at, addMode)
+}
+
+// explain implements Explain on a SyncService of its own.
+func (s *SyncService) explain(streamID int, at time.Time, addMode bool) (*Explanation, error) {
+	streams, err := s.LocalDB.SelectStreams(fmt.Sprintf("select * from streams where id = %d", streamID))
+	if err != nil {
+		return nil, fmt.Errorf("select stream: %w", err)
+	}
+	if len(streams) == 0 {
+		return nil, fmt.Errorf("stream %d not found", streamID)
+	}
+	streamType := streamTypeName(streams[0].StreamType)
+	if streamType == "" {
+		return nil, fmt.Errorf("stream %d has unknown stream_type %d", streamID, streams[0].StreamType)
+	}
+
+	s.run.Store(newRunState(0))
+	serverLocalID := s.LocalServerID()
+	serversOrder := s.GetServersOrder(streamType)
+	if len(serversOrder[streamID]) == 0 {
+		return nil, fmt.Errorf("no servers order for stream %d", streamID)
+	}
+
+	e := &Explanation{
+		StreamID:    streamID,
+		StreamName:  s.Ut.GetStreamNameByID(s.LocalDB, streamID),
+		StreamType:  streamType,
+		At:          at,
+		AddMode:     addMode,
+		WindowStart: s.Ut.BeginOfDay(at),
+		WindowEnd:   s.Ut.EndOfDay(at),
+	}
+	for i, id := range serversOrder[streamID] {
+		e.Servers = append(e.Servers, ExplainServer{ServerID: id, Position: i + 1})
+	}
+
+	sqlStreamID := fmt.Sprintf(" and stream_id=%d ", streamID)
+	sqlStreamType := s.getStreamTypeSQL(streamType)
+	imported, err := s.loadImported(e.WindowStart, e.WindowEnd, sqlStreamID, sqlStreamType, serverLocalID)
+	if err != nil {
+		return nil, fmt.Errorf("select imported records: %w", err)
+	}
+	problems, err := s.LocalDB.SelectRecords(problemRecordsSQL(e.WindowStart, e.WindowEnd, sqlStreamID, sqlStreamType))
+	if err != nil {
+		return nil, fmt.Errorf("select problem records: %w", err)
+	}
+
+	var item model.Record
+	found := false
+	for _, r := range problems {
+		if !at.Before(r.StartedAt) && at.Before(r.EndedAt) {
+			item, found = r, true
+			break
+		}
+	}
+	isGap := false
+	if found {
+		e.Kind = ExplainProblemRecord
+		e.RecordID = item.ID
+		e.Steps = append(e.Steps, fmt.Sprintf("local record %d is a problem record (duration %.2f min, record_rate %.3f)",
+			item.ID, item.Duration, item.RecordRate))
+	} else {
+		var nonRecorded map[int][]model.Period
+		if dbErrors := s.countDBErrors(func() {
+			_, nonRecorded = s.getRecordingStatusInPeriod(s.LocalDB, e.WindowStart, e.WindowEnd, streamType, streamID)
+		}); dbErrors > 0 {
+			return nil, fmt.Errorf("%d DB errors while finding non-recorded periods", dbErrors)
+		}
+		for _, p := range nonRecorded[streamID] {
+			if !at.Before(p.Start) && at.Before(p.End) {
+				p.StreamID = streamID
+				item, found = gapRecord(p), true
+				break
+			}
+		}
+		if !found {
+			e.Kind = ExplainCovered
+			e.Status = "no_need"
+			e.Steps = append(e.Steps, "the stream is recorded at this time by an approved local record that is not a problem record; nothing to sync")
+			return e, nil
+		}
+		isGap = true
+		e.Kind = ExplainGap
+		if item.EndedAt.Sub(item.StartedAt).Seconds() < minGapSeconds {
+			e.Kind = ExplainShortGap
+			e.Start, e.End = item.StartedAt, item.EndedAt
+			e.Status = "no_need"
+			e.Steps = append(e.Steps, fmt.Sprintf("non-recorded period is shorter than %d seconds; not imported", minGapSeconds))
+			return e, nil
+		}
+		e.Steps = append(e.Steps, "time is inside a non-recorded period")
+	}
+	e.Start, e.End = item.StartedAt, item.EndedAt
+
+	s.trace = newExplainTrace()
+	status, imported := s.SyncRecordsFromOtherServers(streamType, serverLocalID, item, imported, serversOrder, isGap, false)
+	ranAddMode := false
+	if status == "no_find" && isGap {
+		if addMode {
+			ranAddMode = true
+			s.trace.addMode = true
+			s.traceStep("no better record found; trying add mode")
+			status, _ = s.AddRecordsFromOtherServers(streamType, serverLocalID, item, imported, serversOrder, false)
+		} else {
+			s.traceStep("add mode is off; the gap stays unfilled")
+		}
+	}
+	e.Status = status
+	e.Steps = append(e.Steps, s.trace.steps...)
+	for i := range e.Servers {
+		if sv, ok := s.trace.servers[e.Servers[i].ServerID]; ok {
+			e.Servers[i].Returned = sv.Returned
+			e.Servers[i].ReturnedAddMode = sv.ReturnedAddMode
+			e.Servers[i].Error = sv.Error
+		}
+	}
+	e.Candidates = s.explainCandidates(e, item, streamType, serverLocalID, isGap, ranAddMode)
+	return e, nil
+}
+
+// explainCandidates lists the records of every server overlapping the item
+// and evaluates the query filters of the selection functions on them.
+func (s *SyncService) explainCandidates(e *Explanation, item model.Record, streamType string, serverLocalID int, isGap, addMode bool) []ExplainCandidate {
+	const layout = "2006-01-02 15:04:05"
+	from := item.StartedAt.Add(-matchDeltaSec * time.Second)
+	to := item.EndedAt.Add(matchDeltaSec * time.Second)
+	hourStart := s.Ut.BeginOfHour(item.StartedAt)
+	hourEnd := s.Ut.EndOfHour(hourStart).Add(3 * time.Minute)
+	if addMode {
+		from = minTime(from, hourStart)
+		to = maxTime(to, hourEnd)
+	}
+	sql := fmt.Sprintf(`
+select * from records
+where started_at < '%s' and ended_at > '%s'
+  and stream_id = %d
+order by started_at
+`, to.Format(layout), from.Format(layout), item.StreamID)
+
+	filters := syncRecordFilters(item, streamType, isGap, hourStart)
+	var addFilters []recordFilter
+	if addMode {
+		addFilters = addModeFilters(item.StreamID, streamType, hourStart, hourEnd)
+	}
+	var out []ExplainCandidate
+	for i := range e.Servers {
+		sv := &e.Servers[i]
+		if sv.Error != "" {
+			continue
+		}
+		d := s.GetRemoteDB(sv.ServerID)
+		if d == nil {
+			sv.Error = "no DB"
+			continue
+		}
+		recs, err := d.SelectRecords(sql)
+		if err != nil {
+			sv.Error = err.Error()
+			continue
+		}
+		for _, r := range recs {
+			key := [2]int{sv.ServerID, r.ID}
+			c := ExplainCandidate{
+				ServerID:        sv.ServerID,
+				RecordID:        r.ID,
+				Path:            r.Path,
+				Start:           r.StartedAt,
+				End:             r.EndedAt,
+				Duration:        r.Duration,
+				RecordRate:      r.RecordRate,
+				PeriodRate:      mathRound(getPeriodRate(r, item.StartedAt, item.EndedAt), 3),
+				Excluded:        excludedBy(filters, r),
+				Returned:        s.trace.returned[key],
+				ReturnedAddMode: s.trace.addRet[key],
+				Best:            s.trace.best[key],
+				Selected:        s.trace.selected[key],
+			}
+			if rate, ok := s.trace.compare[key]; ok {
+				c.CompareRate = &rate
+			}
+			if addMode {
+				c.ExcludedAddMode = excludedBy(addFilters, r)
+			}
+			if originServerID, originRecordID := r.Origin(sv.ServerID); originServerID == serverLocalID {
+				reason := fmt.Sprintf("originates from local record %d", originRecordID)
+				c.Excluded = append(c.Excluded, reason)
+				if addMode {
+					c.ExcludedAddMode = append(c.ExcludedAddMode, reason)
+				}
+			}
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"myproject/internal/repository"
+)
+
+// Readiness check states.
+const (
+	CheckOK   = "ok"
+	CheckFail = "fail"
+)
+
+// Check is one readiness dependency.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of one Check.
+type CheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// Readiness is the outcome of all checks; Status is ok only if every check is.
+type Readiness struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// Ready reports whether every check passed.
+func (r Readiness) Ready() bool { return r.Status == CheckOK }
+
+// RunChecks runs checks concurrently, each limited to timeout, and adds the
+// results of checks already run (done). A check that does not return in time
+// fails; see HealthService.probeOnce for work that can't be interrupted.
+func RunChecks(ctx context.Context, checks []Check, timeout time.Duration, done ...CheckResult) Readiness {
+	results := make([]CheckResult, len(checks), len(checks)+len(done))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c Check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c, timeout)
+		}(i, c)
+	}
+	wg.Wait()
+	results = append(results, done...)
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+
+	r := Readiness{Status: CheckOK, Checks: results}
+	for _, res := range results {
+		if res.Status != CheckOK {
+			r.Status = CheckFail
+		}
+	}
+	return r
+}
+
+func runCheck(ctx context.Context, c Check, timeout time.Duration) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("panic: %v", p)
+			}
+		}()
+		done <- c.Run(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %v", timeout)
+	}
+	res := CheckResult{Name: c.Name, Status: CheckOK, LatencyMS: mathRound(float64(time.Since(start).Microseconds())/1000, 3)}
+	if err != nil {
+		res.Status = CheckFail
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// probe is a run of an uninterruptible probe that callers can wait for.
+type probe struct {
+	done chan struct{}
+	err  error
+}
+
+// HealthService runs the readiness checks of the sync dependencies and keeps
+// the probes still in flight between requests.
+type HealthService struct {
+	newSync func() *SyncService
+
+	mu     sync.Mutex
+	probes map[string]*probe
+}
+
+// NewHealthService creates a HealthService. newSync builds the SyncService
+// whose DBs and recording roots are checked, like a sync run would use them;
+// nil when sync is not configured, which leaves only the caller's checks.
+func NewHealthService(newSync func() *SyncService) *HealthService {
+	return &HealthService{newSync: newSync, probes: make(map[string]*probe)}
+}
+
+// probeOnce runs f unless a probe with the same key is still in flight, in
+// which case it waits for that one. The repository DB and a hung mount can't
+// be interrupted, so at most one goroutine per key is left behind when ctx
+// ends first.
+func (h *HealthService) probeOnce(ctx context.Context, key string, f func() error) error {
+	h.mu.Lock()
+	p, ok := h.probes[key]
+	if !ok {
+		p = &probe{done: make(chan struct{})}
+		h.probes[key] = p
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					p.err = fmt.Errorf("panic: %v", r)
+				}
+				h.mu.Lock()
+				delete(h.probes, key)
+				h.mu.Unlock()
+				close(p.done)
+			}()
+			p.err = f()
+		}()
+	}
+	h.mu.Unlock()
+	select {
+	case <-p.done:
+		return p.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pingDB runs the cheapest query the repository DB interface offers.
+func pingDB(d repository.DB) error {
+	if d == nil {
+		return errors.New("not configured")
+	}
+	_, err := d.SelectStreams("select * from streams limit 1")
+	return err
+}
+
+// checkDir verifies root is a directory the process can list.
+func checkDir(root string) error {
+	f, err := os.Open(root)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
+	}
+	if _, err := f.Readdirnames(1); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// dirCheck checks a recording root, with one probe in flight per root.
+func (h *HealthService) dirCheck(name, root string) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error {
+		return h.probeOnce(ctx, "dir:"+root, func() error { return checkDir(root) })
+	}}
+}
+
+// dbCheck pings the DB d returns, with one probe in flight per check name.
+func (h *HealthService) dbCheck(name string, d func() repository.DB) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error {
+		return h.probeOnce(ctx, "db:"+name, func() error { return pingDB(d()) })
+	}}
+}
+
+// Readiness runs extra and, when sync is configured, the checks of the sync
+// dependencies, each limited to timeout.
+func (h *HealthService) Readiness(ctx context.Context, timeout time.Duration, extra ...Check) Readiness {
+	if h.newSync == nil {
+		return RunChecks(ctx, extra, timeout)
+	}
+	localDB, checks := h.syncChecks(ctx, h.newSync(), timeout)
+	return RunChecks(ctx, append(checks, extra...), timeout, localDB)
+}
+
+// syncChecks runs the local DB check and returns its result with the
+// remaining checks of the sync dependencies of s: the local recording root,
+// the DB of every remote server in the audio and video import orders and the
+// recording root of each of them. Remote servers are only known once the local
+// DB answers, so without it just the local recording check is returned.
+func (h *HealthService) syncChecks(ctx context.Context, s *SyncService, timeout time.Duration) (CheckResult, []Check) {
+	localDB := runCheck(ctx, h.dbCheck("local_db", func() repository.DB { return s.LocalDB }), timeout)
+	checks := []Check{h.dirCheck("local_recording", s.Paths.LocalRoot)}
+	if localDB.Status != CheckOK {
+		return localDB, checks
+	}
+	seen := make(map[int]bool)
+	var servers []int
+	for _, streamType := range []string{"audio", "video"} {
+		for _, id := range s.RemoteServers(s.GetServersOrder(streamType)) {
+			if !seen[id] {
+				seen[id] = true
+				servers = append(servers, id)
+			}
+		}
+	}
+	sort.Ints(servers)
+	local := s.LocalServerID()
+	for _, id := range servers {
+		id := id
+		checks = append(checks,
+			h.dbCheck(fmt.Sprintf("server_%d_db", id), func() repository.DB { return s.GetRemoteDB(id) }),
+			h.dirCheck(fmt.Sprintf("server_%d_recording", id), s.Paths.Root(id, local)),
+		)
+	}
+	return localDB, checks
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"myproject/internal/repository"
+)
+
+// ServerSupply is what one server supplied to a stream in past sync runs:
+// the approved local records that were imported from it.
+type ServerSupply struct {
+	ServerID int     `json:"server_id"`
+	Records  int     `json:"records"`
+	Minutes  float64 `json:"minutes"`
+	AvgRate  float64 `json:"avg_rate"`
+	// Score is the supplied minutes weighted by record rate.
+	Score float64 `json:"score"`
+}
+
+// OrderRecommendation is the recommended server import order of one stream.
+type OrderRecommendation struct {
+	StreamID    int            `json:"stream_id"`
+	StreamName  string         `json:"stream_name,omitempty"`
+	Current     []int          `json:"current"`
+	FromGeneral bool           `json:"from_general"`
+	Recommended []int          `json:"recommended"`
+	Supply      []ServerSupply `json:"supply"`
+	Changed     bool           `json:"changed"`
+}
+
+// FormatServersOrder is the inverse of parseServersOrder: "3,1,2".
+func FormatServersOrder(order []int) string {
+	parts := make([]string, len(order))
+	for i, id := range order {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+func sameOrder(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// RecommendImportOrder ranks the servers of every stream by the records they
+// actually supplied to the local server between start and end. Servers with a
+// higher score come first; servers without history keep their current
+// relative order after them. Only servers of the current order are ranked,
+// so servers that were dropped from it don't come back. Streams with fewer
+// than minRecords imported records keep their current order.
+func (s *SyncService) RecommendImportOrder(start, end time.Time, streamType string, streamID, minRecords int) ([]OrderRecommendation, error) {
+	if s.LocalDB == nil {
+		return nil, fmt.Errorf("no local DB")
+	}
+	serverLocalID := s.LocalServerID()
+	general := parseServersOrder(s.Ut.GetParameter(s.LocalDB, fmt.Sprintf("server_order_%s_records_import", streamType)))
+
+	sqlStreamID := ""
+	if streamID >= 0 {
+		sqlStreamID = fmt.Sprintf(" and id = %d ", streamID)
+	}
+	streams, err := s.LocalDB.SelectStreams(fmt.Sprintf(
+		"select * from streams where enabled=true %s %s order by id",
+		s.getStreamTypeSQL(streamType), sqlStreamID,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("select streams: %w", err)
+	}
+
+	sqlStreamID = ""
+	if streamID >= 0 {
+		sqlStreamID = fmt.Sprintf(" and stream_id = %d ", streamID)
+	}
+	records, err := s.LocalDB.SelectRecords(fmt.Sprintf(`
+select * from records
+where started_at >= '%s' and started_at < '%s'
+  and imported_source_id > 0
+  and is_record_approved = true
+  and is_deleted = false
+  %s %s
+order by stream_id, started_at
+`, start.Format("2006-01-02 15:04:05"), end.Format("2006-01-02 15:04:05"), sqlStreamID, s.getStreamTypeSQL(streamType)))
+	if err != nil {
+		return nil, fmt.Errorf("select imported records: %w", err)
+	}
+
+	supply := make(map[int]map[int]*ServerSupply)
+	counts := make(map[int]int)
+	for _, r := range records {
+		if r.ImportedSourceID == serverLocalID {
+			continue
+		}
+		bySrv, ok := supply[r.StreamID]
+		if !ok {
+			bySrv = make(map[int]*ServerSupply)
+			supply[r.StreamID] = bySrv
+		}
+		sup, ok := bySrv[r.ImportedSourceID]
+		if !ok {
+			sup = &ServerSupply{ServerID: r.ImportedSourceID}
+			bySrv[r.ImportedSourceID] = sup
+		}
+		minutes := r.EndedAt.Sub(r.StartedAt).Minutes()
+		sup.Records++
+		sup.Minutes += minutes
+		sup.AvgRate += r.RecordRate
+		sup.Score += minutes * r.RecordRate
+		counts[r.StreamID]++
+	}
+
+	result := make([]OrderRecommendation, 0, len(streams))
+	for _, stream := range streams {
+		rec := OrderRecommendation{
+			StreamID:   stream.ID,
+			StreamName: s.Ut.GetStreamNameByID(s.LocalDB, stream.ID),
+			Current:    parseServersOrder(stream.ServerImportOrder),
+		}
+		if len(rec.Current) == 0 {
+			rec.Current = general
+			rec.FromGeneral = true
+		}
+		for _, sup := range supply[stream.ID] {
+			sup.AvgRate = mathRound(sup.AvgRate/float64(sup.Records), 3)
+			sup.Minutes = mathRound(sup.Minutes, 2)
+			sup.Score = mathRound(sup.Score, 2)
+			rec.Supply = append(rec.Supply, *sup)
+		}
+		sort.Slice(rec.Supply, func(i, j int) bool {
+			if rec.Supply[i].Score != rec.Supply[j].Score {
+				return rec.Supply[i].Score > rec.Supply[j].Score
+			}
+			return rec.Supply[i].ServerID < rec.Supply[j].ServerID
+		})
+
+		if counts[stream.ID] < minRecords {
+			rec.Recommended = rec.Current
+		} else {
+			current := make(map[int]bool, len(rec.Current))
+			for _, id := range rec.Current {
+				current[id] = true
+			}
+			seen := make(map[int]bool)
+			for _, sup := range rec.Supply {
+				if !current[sup.ServerID] {
+					continue
+				}
+				rec.Recommended = append(rec.Recommended, sup.ServerID)
+				seen[sup.ServerID] = true
+			}
+			for _, id := range rec.Current {
+				if !seen[id] {
+					rec.Recommended = append(rec.Recommended, id)
+				}
+			}
+		}
+		rec.Changed = !sameOrder(rec.Current, rec.Recommended)
+		result = append(result, rec)
+	}
+	return result, nil
+}
+
+// ApplyImportOrder writes the changed recommendations to streams.server_import_order.
+// It returns the number of streams updated.
+func (s *SyncService) ApplyImportOrder(recs []OrderRecommendation) (int, error) {
+	if s.LocalDB == nil {
+		return 0, fmt.Errorf("no local DB")
+	}
+	updated := 0
+	for _, rec := range recs {
+		if !rec.Changed {
+			continue
+		}
+		if err := repository.UpdateStreamServerImportOrder(s.LocalDB, rec.StreamID, FormatServersOrder(rec.Recommended)); err != nil {
+			return updated, fmt.Errorf("stream %d: %w", rec.StreamID, err)
+		}
+		updated++
+	}
+	return updated, nil
+}
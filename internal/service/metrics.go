@@ -0,0 +1,69 @@
+package service
+
+import (
+	"time"
+
+	"myproject/internal/metrics"
+	"myproject/internal/model"
+	"myproject/internal/repository"
+)
+
+var (
+	syncRecordsProcessed = metrics.NewCounterVec("sync_records_processed_total",
+		"Sync items (problem records and non-recorded periods) processed, by status and stream type.", "status", "stream_type")
+	syncBytesCopied = metrics.NewCounterVec("sync_bytes_copied_total",
+		"Bytes of record files copied, by source server.", "server")
+	syncDBQueryDuration = metrics.NewHistogramVec("sync_db_query_duration_seconds",
+		"Latency of sync DB queries, by server (local or remote server ID).", nil, "server")
+	syncGapSecondsFound = metrics.NewCounterVec("sync_gap_seconds_found_total",
+		"Seconds of non-recorded periods found by sync runs, by stream type.", "stream_type")
+	syncGapSecondsFilled = metrics.NewCounterVec("sync_gap_seconds_filled_total",
+		"Seconds of non-recorded periods filled by imported records, by stream type.", "stream_type")
+)
+
+// instrumentedDB observes the latency of every query on the wrapped DB.
+type instrumentedDB struct {
+	repository.DB
+	server string
+}
+
+// instrumentDB wraps d so its queries are timed under the given server label.
+// A nil d stays nil so callers can keep checking for a missing DB.
+func instrumentDB(d repository.DB, server string) repository.DB {
+	if d == nil {
+		return nil
+	}
+	if _, ok := d.(instrumentedDB); ok {
+		return d
+	}
+	return instrumentedDB{DB: d, server: server}
+}
+
+func (d instrumentedDB) observe(start time.Time) {
+	syncDBQueryDuration.Observe(time.Since(start).Seconds(), d.server)
+}
+
+func (d instrumentedDB) SelectRecords(query string) ([]model.Record, error) {
+	defer d.observe(time.Now())
+	return d.DB.SelectRecords(query)
+}
+
+func (d instrumentedDB) SelectStreams(query string) ([]model.Stream, error) {
+	defer d.observe(time.Now())
+	return d.DB.SelectStreams(query)
+}
+
+func (d instrumentedDB) Insert(query string, args ...interface{}) (int64, error) {
+	defer d.observe(time.Now())
+	return d.DB.Insert(query, args...)
+}
+
+func (d instrumentedDB) Update(query string, args ...interface{}) error {
+	defer d.observe(time.Now())
+	return d.DB.Update(query, args...)
+}
+
+// gapSecondsCovered returns how many seconds of [start, end) record r covers.
+func gapSecondsCovered(r model.Record, start, end time.Time) float64 {
+	return getPeriodRate(r, start, end) * end.Sub(start).Seconds()
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// orphanMinAge keeps files that may still be written, or whose record is not
+// inserted yet, out of the orphan scan.
+const orphanMinAge = time.Hour
+
+// OrphanFile is a file under the local recording root that no approved or
+// checked record owns.
+type OrphanFile struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Reason  string    `json:"reason"`
+	// MovedTo is set once the file is in quarantine.
+	MovedTo string `json:"moved_to,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// OrphanReport is the outcome of ScanOrphans.
+type OrphanReport struct {
+	Root         string       `json:"root"`
+	Start        time.Time    `json:"start"`
+	End          time.Time    `json:"end"`
+	ScannedFiles int          `json:"scanned_files"`
+	ScannedBytes int64        `json:"scanned_bytes"`
+	OrphanBytes  int64        `json:"orphan_bytes"`
+	MovedBytes   int64        `json:"moved_bytes"`
+	Orphans      []OrphanFile `json:"orphans"`
+}
+
+// recordFile is the file name prefix a record owns: CopyRecords copies every
+// file matching the record path without extension.
+type recordFile struct {
+	prefix string
+	id     int
+	owns   bool
+}
+
+// ScanOrphans walks the local recording root for files modified between start
+// and end and reports the ones that do not belong to an approved or checked
+// record. Owners are looked up by the directory of each scanned file, however
+// long ago their recording started. skipDir, if set, is not walked (the
+// quarantine directory). A walk error fails the whole scan.
+func (s *SyncService) ScanOrphans(start, end time.Time, skipDir string) (*OrphanReport, error) {
+	root := filepath.Clean(s.Paths.LocalRoot)
+	report := &OrphanReport{Root: root, Start: start, End: end}
+	if skipDir != "" {
+		skipDir = filepath.Clean(skipDir)
+	}
+	cutoff := time.Now().Add(-orphanMinAge)
+	type scannedFile struct {
+		path string
+		name string
+		fi   fs.FileInfo
+	}
+	byDir := make(map[string][]scannedFile)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if skipDir != "" && path == skipDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		mt := fi.ModTime()
+		if mt.Before(start) || !mt.Before(end) || mt.After(cutoff) {
+			return nil
+		}
+		report.ScannedFiles++
+		report.ScannedBytes += fi.Size()
+		dir := filepath.Dir(path)
+		byDir[dir] = append(byDir[dir], scannedFile{path: path, name: d.Name(), fi: fi})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk %s: %w", root, err)
+	}
+
+	for dir, files := range byDir {
+		owners, err := s.dirRecordFiles(root, dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			reason := orphanReason(owners, f.name)
+			if reason == "" {
+				continue
+			}
+			rel, _ := filepath.Rel(root, f.path)
+			report.Orphans = append(report.Orphans, OrphanFile{Path: rel, Size: f.fi.Size(), ModTime: f.fi.ModTime(), Reason: reason})
+			report.OrphanBytes += f.fi.Size()
+		}
+	}
+	sort.Slice(report.Orphans, func(i, j int) bool { return report.Orphans[i].Path < report.Orphans[j].Path })
+	return report, nil
+}
+
+// dirRecordFiles returns the record files of every record whose path lies
+// directly in dir under root.
+func (s *SyncService) dirRecordFiles(root, dir string) ([]recordFile, error) {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return nil, err
+	}
+	cond := "path not like '%/%'"
+	if rel != "." {
+		prefix := filepath.ToSlash(rel) + "/"
+		prefix = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`, "'", "''").Replace(prefix)
+		cond = fmt.Sprintf("path like '%s%%'", prefix)
+	}
+	records, err := s.LocalDB.SelectRecords(fmt.Sprintf(`
+select * from records
+where %s
+order by id
+`, cond))
+	if err != nil {
+		return nil, fmt.Errorf("select records of %s: %w", dir, err)
+	}
+	var files []recordFile
+	for _, r := range records {
+		full, err := resolveRecordPath(root, r.Path)
+		if err != nil || filepath.Dir(full) != dir {
+			continue
+		}
+		name := filepath.Base(full)
+		files = append(files, recordFile{
+			prefix: strings.TrimSuffix(name, filepath.Ext(name)),
+			id:     r.ID,
+			owns:   r.IsRecordApproved || r.IsRecordChecked,
+		})
+	}
+	return files, nil
+}
+
+// orphanReason returns why a file named name is an orphan among the record
+// files of its directory, or "" when a record owns it.
+func orphanReason(files []recordFile, name string) string {
+	disabled := -1
+	for _, f := range files {
+		if !strings.HasPrefix(name, f.prefix) {
+			continue
+		}
+		if f.owns {
+			return ""
+		}
+		disabled = f.id
+	}
+	if disabled > 0 {
+		return fmt.Sprintf("record %d is neither approved nor checked", disabled)
+	}
+	return "no record"
+}
+
+// QuarantineOrphans moves the orphans of report into dir, keeping their path
+// relative to the recording root. Files that can't be moved keep an error.
+func (s *SyncService) QuarantineOrphans(report *OrphanReport, dir string) {
+	for i := range report.Orphans {
+		o := &report.Orphans[i]
+		dst := filepath.Join(dir, o.Path)
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			o.Error = err.Error()
+			continue
+		}
+		if _, err := os.Lstat(dst); err == nil {
+			o.Error = "already in quarantine: " + dst
+			continue
+		}
+		if err := os.Rename(filepath.Join(report.Root, o.Path), dst); err != nil {
+			o.Error = err.Error()
+			continue
+		}
+		o.MovedTo = dst
+		report.MovedBytes += o.Size
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pkgutils "myproject/pkg/utils"
+)
+
+// PathLayout describes where record files live on the local server and on
+// the mounted file systems of the remote servers.
+type PathLayout struct {
+	// LocalRoot is the recording directory of the local server.
+	LocalRoot string
+	// RemoteRoot is a fmt pattern taking the remote server ID.
+	RemoteRoot string
+}
+
+// DefaultPathLayout returns the layout used on the production servers.
+func DefaultPathLayout() PathLayout {
+	return PathLayout{
+		LocalRoot:  "/home/neurotime/stream_analyse/recording/",
+		RemoteRoot: "/mnt/fs_svr%d/recording/",
+	}
+}
+
+// PathLayoutFromEnv overrides the roots of l from SYNC_LOCAL_ROOT and
+// SYNC_REMOTE_ROOT (a pattern with %d for the server ID) when they are set.
+// sync-cli and myapp both apply it, so their runs and checks see the same roots.
+func PathLayoutFromEnv(l PathLayout) PathLayout {
+	l.LocalRoot = pkgutils.CoalesceString(os.Getenv("SYNC_LOCAL_ROOT"), l.LocalRoot)
+	l.RemoteRoot = pkgutils.CoalesceString(os.Getenv("SYNC_REMOTE_ROOT"), l.RemoteRoot)
+	return l
+}
+
+// Root returns the recording root of server svr as seen from svrLocal.
+func (l PathLayout) Root(svr, svrLocal int) string {
+	if svr == svrLocal {
+		return l.LocalRoot
+	}
+	return fmt.Sprintf(l.RemoteRoot, svr)
+}
+
+// resolveRecordPath joins a record path from the records table onto root.
+// Absolute paths and paths that leave root after cleaning are rejected.
+func resolveRecordPath(root, recordPath string) (string, error) {
+	p := strings.TrimSpace(recordPath)
+	if p == "" {
+		return "", fmt.Errorf("empty record path")
+	}
+	if strings.HasPrefix(p, "/") || filepath.IsAbs(p) || filepath.VolumeName(p) != "" {
+		return "", fmt.Errorf("absolute record path %q", recordPath)
+	}
+	cleanRoot := filepath.Clean(root)
+	prefix := cleanRoot
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+	full := filepath.Join(cleanRoot, p)
+	if !strings.HasPrefix(full, prefix) {
+		return "", fmt.Errorf("record path %q escapes %s", recordPath, root)
+	}
+	return full, nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveRecordPath(t *testing.T) {
+	tests := []struct {
+		name string
+		root string
+		path string
+		want string
+		err  string
+	}{
+		{name: "relative path", root: "/rec", path: "2025/01/a.mp3", want: "/rec/2025/01/a.mp3"},
+		{name: "root with trailing slash", root: "/rec/", path: "2025/a.mp3", want: "/rec/2025/a.mp3"},
+		{name: "dot slash prefix", root: "/rec", path: "./2025/a.mp3", want: "/rec/2025/a.mp3"},
+		{name: "dot dot inside root", root: "/rec", path: "2025/../2024/a.mp3", want: "/rec/2024/a.mp3"},
+		{name: "surrounding spaces", root: "/rec", path: " a.mp3 ", want: "/rec/a.mp3"},
+		{name: "dot dot escape", root: "/rec", path: "../etc/passwd", err: "escapes"},
+		{name: "nested escape", root: "/rec/", path: "2025/../../etc/passwd", err: "escapes"},
+		{name: "sibling with the root as prefix", root: "/rec", path: "../rec2/a.mp3", err: "escapes"},
+		{name: "sibling with trailing slash root", root: "/rec/", path: "../rec2/a.mp3", err: "escapes"},
+		{name: "root itself", root: "/rec", path: ".", err: "escapes"},
+		{name: "absolute path", root: "/rec", path: "/etc/passwd", err: "absolute"},
+		{name: "absolute path inside root", root: "/rec", path: "/rec/a.mp3", err: "absolute"},
+		{name: "empty path", root: "/rec", path: "  ", err: "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveRecordPath(tt.root, tt.path)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("resolveRecordPath(%q, %q) = %q, %v; want error %q", tt.root, tt.path, got, err, tt.err)
+				}
+				return
+			}
+			if err != nil || got != filepath.FromSlash(tt.want) {
+				t.Errorf("resolveRecordPath(%q, %q) = %q, %v; want %q", tt.root, tt.path, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"myproject/internal/model"
+)
+
+// clipPeriods cuts periods to [start, end) and drops empty ones.
+func clipPeriods(periods []model.Period, start, end time.Time) []model.Period {
+	var out []model.Period
+	for _, p := range periods {
+		p.Start = maxTime(p.Start, start)
+		p.End = minTime(p.End, end)
+		if p.End.After(p.Start) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// mergePeriods sorts periods and joins overlapping or touching ones.
+func mergePeriods(periods []model.Period) []model.Period {
+	if len(periods) == 0 {
+		return nil
+	}
+	sorted := append([]model.Period(nil), periods...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+	out := []model.Period{sorted[0]}
+	for _, p := range sorted[1:] {
+		last := &out[len(out)-1]
+		if !p.Start.After(last.End) {
+			last.End = maxTime(last.End, p.End)
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// periodsDuration returns the total length of periods, which must not overlap.
+func periodsDuration(periods []model.Period) time.Duration {
+	var d time.Duration
+	for _, p := range periods {
+		d += p.End.Sub(p.Start)
+	}
+	return d
+}
+
+// intersectPeriods returns the parts of a that are also covered by b.
+// Both inputs must be merged (sorted, non-overlapping).
+func intersectPeriods(a, b []model.Period) []model.Period {
+	var out []model.Period
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		start := maxTime(a[i].Start, b[j].Start)
+		end := minTime(a[i].End, b[j].End)
+		if end.After(start) {
+			out = append(out, model.Period{Start: start, End: end})
+		}
+		if a[i].End.Before(b[j].End) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return out
+}
+
+// subtractPeriods returns the parts of a not covered by b.
+// Both inputs must be merged (sorted, non-overlapping).
+func subtractPeriods(a, b []model.Period) []model.Period {
+	var out []model.Period
+	j := 0
+	for _, p := range a {
+		cur := p.Start
+		for j < len(b) && !b[j].End.After(cur) {
+			j++
+		}
+		for k := j; k < len(b) && b[k].Start.Before(p.End); k++ {
+			if b[k].Start.After(cur) {
+				out = append(out, model.Period{Start: cur, End: b[k].Start})
+			}
+			cur = maxTime(cur, b[k].End)
+		}
+		if p.End.After(cur) {
+			out = append(out, model.Period{Start: cur, End: p.End})
+		}
+	}
+	return out
+}
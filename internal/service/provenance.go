@@ -0,0 +1,91 @@
+package service
+
+import (
+	"fmt"
+
+	"myproject/internal/model"
+	"myproject/internal/repository"
+)
+
+// LineageHop is one server's copy of a record in a provenance chain.
+type LineageHop struct {
+	ServerID int
+	Record   model.Record
+}
+
+// maxLineageHops bounds the chain walk in case of inconsistent import columns.
+const maxLineageHops = 32
+
+// dropLocalOrigin removes candidates read from serverID that were first
+// recorded on the local server; importing them back would only create a loop.
+func dropLocalOrigin(recs []model.Record, serverID, serverLocalID int) []model.Record {
+	kept := recs[:0]
+	for _, r := range recs {
+		if originServerID, originRecordID := r.Origin(serverID); originServerID == serverLocalID {
+			fmt.Printf("  > skip record %d from db-%d: originates from local record %d\n", r.ID, serverID, originRecordID)
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// isImportedWithOrigin reports whether r read from serverID, or the record it
+// was originally imported from, is already in imported.
+func isImportedWithOrigin(serverID int, r model.Record, imported map[int][]int) bool {
+	if isRecordInImported(serverID, r.ID, imported) {
+		return true
+	}
+	originServerID, originRecordID := r.Origin(serverID)
+	return isRecordInImported(originServerID, originRecordID, imported)
+}
+
+// markImported adds r read from serverID and its origin to imported.
+func (s *SyncService) markImported(serverID int, r model.Record, imported map[int][]int) map[int][]int {
+	imported = s.addRecordToImported(serverID, r.ID, imported)
+	if originServerID, originRecordID := r.Origin(serverID); originServerID != serverID {
+		imported = s.addRecordToImported(originServerID, originRecordID, imported)
+	}
+	return imported
+}
+
+func (s *SyncService) dbForServer(serverID, serverLocalID int) repository.DB {
+	if serverID == serverLocalID {
+		return s.LocalDB
+	}
+	return s.GetRemoteDB(serverID)
+}
+
+// Lineage follows imported_source_id / imported_record_id from a local record
+// back through every server it was imported from. The first hop is the local
+// record; the last one is the recording the chain could be resolved to.
+func (s *SyncService) Lineage(recordID int) ([]LineageHop, error) {
+	serverLocalID := s.LocalServerID()
+	serverID := serverLocalID
+	seen := make(map[[2]int]bool)
+	var hops []LineageHop
+	for len(hops) < maxLineageHops {
+		if seen[[2]int{serverID, recordID}] {
+			return hops, fmt.Errorf("provenance loop at server %d record %d", serverID, recordID)
+		}
+		seen[[2]int{serverID, recordID}] = true
+		d := s.dbForServer(serverID, serverLocalID)
+		if d == nil {
+			return hops, fmt.Errorf("no DB for server %d", serverID)
+		}
+		recs, err := d.SelectRecords(fmt.Sprintf("select * from records where id = %d", recordID))
+		if err != nil {
+			return hops, fmt.Errorf("server %d: %w", serverID, err)
+		}
+		if len(recs) == 0 {
+			return hops, fmt.Errorf("record %d not found on server %d", recordID, serverID)
+		}
+		r := recs[0]
+		hops = append(hops, LineageHop{ServerID: serverID, Record: r})
+		if r.ImportedSourceID <= 0 || r.ImportedRecordID <= 0 {
+			return hops, nil
+		}
+		serverID, recordID = r.ImportedSourceID, r.ImportedRecordID
+	}
+	return hops, fmt.Errorf("lineage longer than %d hops", maxLineageHops)
+}
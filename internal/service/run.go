@@ -0,0 +1,96 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RunReport summarises a sync run; it can be read while the run is in progress.
+type RunReport struct {
+	LocalServerID int            `json:"local_server_id"`
+	TaskID        int            `json:"task_id"`
+	SyncStart     time.Time      `json:"sync_start"`
+	SyncEnd       time.Time      `json:"sync_end"`
+	Processed     int            `json:"processed"`
+	Total         int            `json:"total"`
+	Statuses      map[string]int `json:"statuses"`
+	DBErrors      int            `json:"db_errors"`
+	OutOfSpace    bool           `json:"out_of_space"`
+	BytesNeeded   int64          `json:"bytes_needed"`
+}
+
+// runState holds bookkeeping for a single StartRecordProcessing run.
+// The run goroutine is the only writer; writes take mu so Report can be
+// called from other goroutines.
+type runState struct {
+	minFreeBytes int64
+
+	mu     sync.Mutex
+	report RunReport
+}
+
+func newRunState(minFreeBytes int64) *runState {
+	return &runState{minFreeBytes: minFreeBytes, report: RunReport{Statuses: make(map[string]int)}}
+}
+
+func (rs *runState) update(f func(r *RunReport)) {
+	rs.mu.Lock()
+	f(&rs.report)
+	rs.mu.Unlock()
+}
+
+// Report returns a snapshot of the current or last run of s.
+func (s *SyncService) Report() RunReport {
+	rs := s.run.Load()
+	if rs == nil {
+		return RunReport{Statuses: map[string]int{}}
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	r := rs.report
+	r.Statuses = make(map[string]int, len(rs.report.Statuses))
+	for k, v := range rs.report.Statuses {
+		r.Statuses[k] = v
+	}
+	return r
+}
+
+// countStatus records the outcome of item n of nn.
+func (s *SyncService) countStatus(status string, n, nn int) {
+	s.run.Load().update(func(r *RunReport) {
+		r.Statuses[status]++
+		r.Processed = n
+		r.Total = nn
+	})
+}
+
+// outOfSpace reports whether the free space guard has stopped the current run.
+func (s *SyncService) outOfSpace() bool {
+	rs := s.run.Load()
+	if rs == nil {
+		return false
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.report.OutOfSpace
+}
+
+// dbError logs a DB error from where and counts it against the current run.
+func (s *SyncService) dbError(where string, err error) {
+	fmt.Printf("DB error in %s: %v\n", where, err)
+	if rs := s.run.Load(); rs != nil {
+		rs.update(func(r *RunReport) { r.DBErrors++ })
+	}
+}
+
+// countDBErrors runs f outside of a sync run and returns how many DB errors it logged.
+func (s *SyncService) countDBErrors(f func()) int {
+	rs := newRunState(0)
+	prev := s.run.Swap(rs)
+	defer s.run.Store(prev)
+	f()
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.report.DBErrors
+}
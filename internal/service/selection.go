@@ -0,0 +1,130 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"myproject/internal/model"
+)
+
+// recordFilter is one condition of a candidate selection query: the SQL the
+// servers evaluate and the same test on a record, which Explain uses to tell
+// why a record was not returned.
+type recordFilter struct {
+	sql string
+	// exclude returns why r fails the condition, or "" when it passes.
+	exclude func(r model.Record) string
+}
+
+func filterIf(sql string, fails func(r model.Record) bool, reason string) recordFilter {
+	return recordFilter{sql: sql, exclude: func(r model.Record) string {
+		if fails(r) {
+			return reason
+		}
+		return ""
+	}}
+}
+
+// whereSQL joins the conditions of filters for a where clause.
+func whereSQL(filters []recordFilter) string {
+	conds := make([]string, len(filters))
+	for i, f := range filters {
+		conds[i] = f.sql
+	}
+	return strings.Join(conds, "\n  and ")
+}
+
+// excludedBy lists why r fails filters, nil when the query returns it.
+func excludedBy(filters []recordFilter, r model.Record) []string {
+	var reasons []string
+	for _, f := range filters {
+		if reason := f.exclude(r); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+// candidateFilters are the record flags both selection queries require.
+func candidateFilters(streamID int, streamType string) []recordFilter {
+	f := []recordFilter{
+		filterIf(fmt.Sprintf("stream_id = %d", streamID),
+			func(r model.Record) bool { return r.StreamID != streamID }, fmt.Sprintf("not stream %d", streamID)),
+		filterIf("is_record_approved = true", func(r model.Record) bool { return !r.IsRecordApproved }, "not approved"),
+		filterIf("converted_to_mp3 = true", func(r model.Record) bool { return !r.ConvertedToMP3 }, "not converted_to_mp3"),
+	}
+	if streamType == "video" {
+		f = append(f, filterIf("converted_to_low = true", func(r model.Record) bool { return !r.ConvertedToLow }, "not converted_to_low"))
+	}
+	return f
+}
+
+// syncRecordFilters are the conditions SyncRecordsFromOtherServers selects
+// candidates for record with: records overlapping it when it is a
+// non-recorded period (isGap), else longer records with a better rate around
+// it. Candidates never start before startedHour, the hour of record.
+func syncRecordFilters(record model.Record, streamType string, isGap bool, startedHour time.Time) []recordFilter {
+	const layout = "2006-01-02 15:04:05"
+	var f []recordFilter
+	if isGap {
+		f = append(f, filterIf(fmt.Sprintf("started_at < '%s' and ended_at > '%s'", record.EndedAt.Format(layout), record.StartedAt.Format(layout)),
+			func(r model.Record) bool {
+				return !r.StartedAt.Before(record.EndedAt) || !r.EndedAt.After(record.StartedAt)
+			},
+			"does not overlap the non-recorded period"))
+	} else {
+		delta := matchDeltaSec * time.Second
+		start1, start2 := record.StartedAt.Add(-delta), record.StartedAt.Add(delta)
+		duration, recordRate := matchThresholds(record)
+		f = append(f,
+			filterIf(fmt.Sprintf("(((started_at - (interval '1 sec' * %d)) < '%s') or (started_at between '%s' and '%s'))",
+				matchDeltaSec, record.StartedAt.Format(layout), start1.Format(layout), start2.Format(layout)),
+				func(r model.Record) bool {
+					return !r.StartedAt.Add(-delta).Before(record.StartedAt) && (r.StartedAt.Before(start1) || r.StartedAt.After(start2))
+				}, "starts after the record"),
+			filterIf(fmt.Sprintf("'%s' < least(ended_at, started_at + (interval '1 min' * duration)) + (interval '1 sec' * %d)",
+				record.EndedAt.Format(layout), matchDeltaSec),
+				func(r model.Record) bool {
+					end := minTime(r.EndedAt, r.StartedAt.Add(time.Duration(r.Duration*float64(time.Minute))))
+					return !record.EndedAt.Before(end.Add(delta))
+				}, "ends before the record"),
+			recordFilter{sql: fmt.Sprintf("duration > %f", duration), exclude: func(r model.Record) string {
+				if r.Duration <= duration {
+					return fmt.Sprintf("duration %.2f <= %.2f", r.Duration, duration)
+				}
+				return ""
+			}},
+			recordFilter{sql: fmt.Sprintf("record_rate > %f", recordRate), exclude: func(r model.Record) string {
+				if r.RecordRate <= recordRate {
+					return fmt.Sprintf("record_rate %.3f <= %.3f", r.RecordRate, recordRate)
+				}
+				return ""
+			}},
+		)
+	}
+	f = append(f, filterIf(fmt.Sprintf("started_at >= '%s'", startedHour.Format(layout)),
+		func(r model.Record) bool { return r.StartedAt.Before(startedHour) }, "starts before the hour of the item"))
+	f = append(f, candidateFilters(record.StreamID, streamType)...)
+	return append(f,
+		filterIf("is_deleted = false", func(r model.Record) bool { return r.IsDeleted }, "deleted"),
+		recordFilter{sql: "return_code=0", exclude: func(r model.Record) string {
+			if r.ReturnCode != 0 {
+				return fmt.Sprintf("return_code=%d", r.ReturnCode)
+			}
+			return ""
+		}},
+	)
+}
+
+// addModeFilters are the conditions AddRecordsFromOtherServers selects
+// records of streamID with: any record inside the hour window.
+func addModeFilters(streamID int, streamType string, hourStart, hourEnd time.Time) []recordFilter {
+	const layout = "2006-01-02 15:04:05"
+	f := []recordFilter{
+		filterIf(fmt.Sprintf("('%s' < started_at and ended_at < '%s')", hourStart.Format(layout), hourEnd.Format(layout)),
+			func(r model.Record) bool { return !hourStart.Before(r.StartedAt) || !r.EndedAt.Before(hourEnd) }, "not inside the hour"),
+		filterIf("duration > 0", func(r model.Record) bool { return r.Duration <= 0 }, "duration <= 0"),
+	}
+	return append(f, candidateFilters(streamID, streamType)...)
+}
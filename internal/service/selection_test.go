@@ -0,0 +1,427 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+
+	"myproject/internal/model"
+)
+
+// sqlEval evaluates a where clause built by the selection filters on one
+// record, so the tests can compare the SQL the servers run with the record
+// tests Explain reports. It knows only the SQL those filters produce:
+// and/or, comparisons, between, + - * on timestamps and intervals, least()
+// and the records columns they use.
+type sqlEval struct {
+	toks []string
+	pos  int
+	r    model.Record
+}
+
+func evalWhere(where string, r model.Record) (bool, error) {
+	toks, err := sqlTokens(where)
+	if err != nil {
+		return false, err
+	}
+	e := &sqlEval{toks: toks, r: r}
+	v, err := e.or()
+	if err != nil {
+		return false, err
+	}
+	if e.pos != len(e.toks) {
+		return false, fmt.Errorf("unexpected %q", e.toks[e.pos])
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("where clause is %T, not bool", v)
+	}
+	return b, nil
+}
+
+func sqlTokens(s string) ([]string, error) {
+	var toks []string
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'':
+			j := strings.IndexByte(s[i+1:], '\'')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			toks = append(toks, s[i:i+j+2])
+			i += j + 2
+		case unicode.IsLetter(c) || c == '_' || unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_' || s[j] == '.') {
+				j++
+			}
+			toks = append(toks, strings.ToLower(s[i:j]))
+			i = j
+		case strings.HasPrefix(s[i:], "<=") || strings.HasPrefix(s[i:], ">=") || strings.HasPrefix(s[i:], "<>"):
+			toks = append(toks, s[i:i+2])
+			i += 2
+		case strings.ContainsRune("()<>=+-*,", c):
+			toks = append(toks, string(c))
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", c, i)
+		}
+	}
+	return toks, nil
+}
+
+func (e *sqlEval) peek() string {
+	if e.pos < len(e.toks) {
+		return e.toks[e.pos]
+	}
+	return ""
+}
+
+func (e *sqlEval) expect(tok string) error {
+	if e.peek() != tok {
+		return fmt.Errorf("expected %q, got %q", tok, e.peek())
+	}
+	e.pos++
+	return nil
+}
+
+func (e *sqlEval) or() (interface{}, error) {
+	return e.logical("or", e.and, func(a, b bool) bool { return a || b })
+}
+
+func (e *sqlEval) and() (interface{}, error) {
+	return e.logical("and", e.cmp, func(a, b bool) bool { return a && b })
+}
+
+func (e *sqlEval) logical(op string, next func() (interface{}, error), f func(a, b bool) bool) (interface{}, error) {
+	v, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for e.peek() == op {
+		e.pos++
+		w, err := next()
+		if err != nil {
+			return nil, err
+		}
+		a, ok1 := v.(bool)
+		b, ok2 := w.(bool)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%s of %T and %T", op, v, w)
+		}
+		v = f(a, b)
+	}
+	return v, nil
+}
+
+func (e *sqlEval) cmp() (interface{}, error) {
+	v, err := e.add()
+	if err != nil {
+		return nil, err
+	}
+	switch op := e.peek(); op {
+	case "<", ">", "<=", ">=", "=", "<>":
+		e.pos++
+		w, err := e.add()
+		if err != nil {
+			return nil, err
+		}
+		c, err := compare(v, w)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]bool{"<": c < 0, ">": c > 0, "<=": c <= 0, ">=": c >= 0, "=": c == 0, "<>": c != 0}[op], nil
+	case "between":
+		e.pos++
+		lo, err := e.add()
+		if err != nil {
+			return nil, err
+		}
+		if err := e.expect("and"); err != nil {
+			return nil, err
+		}
+		hi, err := e.add()
+		if err != nil {
+			return nil, err
+		}
+		c1, err := compare(v, lo)
+		if err != nil {
+			return nil, err
+		}
+		c2, err := compare(v, hi)
+		if err != nil {
+			return nil, err
+		}
+		return c1 >= 0 && c2 <= 0, nil
+	}
+	return v, nil
+}
+
+func (e *sqlEval) add() (interface{}, error) {
+	v, err := e.mul()
+	if err != nil {
+		return nil, err
+	}
+	for e.peek() == "+" || e.peek() == "-" {
+		op := e.peek()
+		e.pos++
+		w, err := e.mul()
+		if err != nil {
+			return nil, err
+		}
+		t, ok1 := asTime(v)
+		d, ok2 := w.(time.Duration)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%T %s %T", v, op, w)
+		}
+		if op == "-" {
+			d = -d
+		}
+		v = t.Add(d)
+	}
+	return v, nil
+}
+
+func (e *sqlEval) mul() (interface{}, error) {
+	v, err := e.primary()
+	if err != nil {
+		return nil, err
+	}
+	for e.peek() == "*" {
+		e.pos++
+		w, err := e.primary()
+		if err != nil {
+			return nil, err
+		}
+		d, ok1 := v.(time.Duration)
+		n, ok2 := w.(float64)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%T * %T", v, w)
+		}
+		v = time.Duration(float64(d) * n)
+	}
+	return v, nil
+}
+
+func (e *sqlEval) primary() (interface{}, error) {
+	tok := e.peek()
+	e.pos++
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end")
+	case tok == "(":
+		v, err := e.or()
+		if err != nil {
+			return nil, err
+		}
+		return v, e.expect(")")
+	case tok == "true" || tok == "false":
+		return tok == "true", nil
+	case tok[0] == '\'':
+		return tok[1 : len(tok)-1], nil
+	case tok == "interval":
+		lit := e.peek()
+		e.pos++
+		switch lit {
+		case "'1 sec'":
+			return time.Second, nil
+		case "'1 min'":
+			return time.Minute, nil
+		}
+		return nil, fmt.Errorf("unknown interval %s", lit)
+	case tok == "least":
+		if err := e.expect("("); err != nil {
+			return nil, err
+		}
+		a, err := e.add()
+		if err != nil {
+			return nil, err
+		}
+		if err := e.expect(","); err != nil {
+			return nil, err
+		}
+		b, err := e.add()
+		if err != nil {
+			return nil, err
+		}
+		if err := e.expect(")"); err != nil {
+			return nil, err
+		}
+		if c, err := compare(a, b); err != nil || c <= 0 {
+			return a, err
+		}
+		return b, nil
+	case unicode.IsDigit(rune(tok[0])):
+		return strconv.ParseFloat(tok, 64)
+	}
+	return e.column(tok)
+}
+
+func (e *sqlEval) column(name string) (interface{}, error) {
+	r := e.r
+	switch name {
+	case "stream_id":
+		return float64(r.StreamID), nil
+	case "started_at":
+		return r.StartedAt, nil
+	case "ended_at":
+		return r.EndedAt, nil
+	case "duration":
+		return r.Duration, nil
+	case "record_rate":
+		return r.RecordRate, nil
+	case "return_code":
+		return float64(r.ReturnCode), nil
+	case "is_record_approved":
+		return r.IsRecordApproved, nil
+	case "converted_to_mp3":
+		return r.ConvertedToMP3, nil
+	case "converted_to_low":
+		return r.ConvertedToLow, nil
+	case "is_deleted":
+		return r.IsDeleted, nil
+	}
+	return nil, fmt.Errorf("unknown column %q", name)
+}
+
+// asTime reads a timestamp value or literal.
+func asTime(v interface{}) (time.Time, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		t, err := time.Parse("2006-01-02 15:04:05", v)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+func compare(a, b interface{}) (int, error) {
+	if x, ok := a.(float64); ok {
+		if y, ok := b.(float64); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	if x, ok := a.(bool); ok {
+		if y, ok := b.(bool); ok {
+			if x == y {
+				return 0, nil
+			}
+			return 1, nil
+		}
+	}
+	x, ok1 := asTime(a)
+	y, ok2 := asTime(b)
+	if !ok1 || !ok2 {
+		return 0, fmt.Errorf("can't compare %T and %T", a, b)
+	}
+	return x.Compare(y), nil
+}
+
+// selectionCandidates varies every column the filters test around the
+// boundaries of item.
+func selectionCandidates(item model.Record) []model.Record {
+	starts := []time.Duration{-16 * time.Minute, -15 * time.Minute, -10 * time.Minute, -11 * time.Second,
+		-10 * time.Second, -9 * time.Second, 0, 10 * time.Second, 11 * time.Second, 20 * time.Minute}
+	ends := []time.Duration{-20 * time.Minute, -11 * time.Second, -10 * time.Second, -9 * time.Second,
+		0, 10 * time.Second, 5 * time.Minute, 16 * time.Minute}
+	durations := []float64{0, 5, 20.5, 60}
+	rates := []float64{0.5, 0.8, 0.801, 0.9}
+	flags := []func(r *model.Record){
+		func(r *model.Record) {},
+		func(r *model.Record) { r.StreamID++ },
+		func(r *model.Record) { r.IsRecordApproved = false },
+		func(r *model.Record) { r.ConvertedToMP3 = false },
+		func(r *model.Record) { r.ConvertedToLow = false },
+		func(r *model.Record) { r.IsDeleted = true },
+		func(r *model.Record) { r.ReturnCode = 1 },
+	}
+	var out []model.Record
+	id := 0
+	for _, s := range starts {
+		for _, e := range ends {
+			for _, d := range durations {
+				for _, rate := range rates {
+					for _, f := range flags {
+						id++
+						r := model.Record{ID: id, StreamID: item.StreamID,
+							StartedAt: item.StartedAt.Add(s), EndedAt: item.EndedAt.Add(e),
+							Duration: d, RecordRate: rate,
+							IsRecordApproved: true, ConvertedToMP3: true, ConvertedToLow: true}
+						f(&r)
+						out = append(out, r)
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// TestSelectionSQLMatchesExplain runs the SQL of every selection filter and
+// the record test Explain uses on the same records: they must agree on each
+// filter and on the whole query.
+func TestSelectionSQLMatchesExplain(t *testing.T) {
+	at := func(s string) time.Time {
+		t, err := time.Parse("2006-01-02 15:04:05", s)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+	item := model.Record{ID: -1, StreamID: 7, StartedAt: at("2025-01-01 10:15:00"), EndedAt: at("2025-01-01 10:35:30"),
+		Duration: 20.5, RecordRate: 0.8}
+	hourStart, hourEnd := at("2025-01-01 10:00:00"), at("2025-01-01 11:02:59")
+	sets := []struct {
+		name    string
+		filters []recordFilter
+	}{
+		{"problem record audio", syncRecordFilters(item, "audio", false, hourStart)},
+		{"problem record video", syncRecordFilters(item, "video", false, hourStart)},
+		{"gap", syncRecordFilters(item, "audio", true, hourStart)},
+		{"add mode", addModeFilters(item.StreamID, "video", hourStart, hourEnd)},
+	}
+	candidates := selectionCandidates(item)
+	for _, set := range sets {
+		t.Run(set.name, func(t *testing.T) {
+			returned := 0
+			for _, r := range candidates {
+				for _, f := range set.filters {
+					sqlPasses, err := evalWhere(f.sql, r)
+					if err != nil {
+						t.Fatalf("%s: %v", f.sql, err)
+					}
+					reason := f.exclude(r)
+					if sqlPasses != (reason == "") {
+						t.Errorf("record %+v: SQL %q passes=%v, Explain reason %q", r, f.sql, sqlPasses, reason)
+					}
+				}
+				selected, err := evalWhere(whereSQL(set.filters), r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if reasons := excludedBy(set.filters, r); selected != (len(reasons) == 0) {
+					t.Errorf("record %+v: query selects it=%v, Explain excludes it for %v", r, selected, reasons)
+				}
+				if selected {
+					returned++
+				}
+			}
+			if returned == 0 || returned == len(candidates) {
+				t.Errorf("query returns %d of %d candidates, want some of them", returned, len(candidates))
+			}
+		})
+	}
+}
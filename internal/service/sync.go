@@ -1,11 +1,14 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"myproject/internal/model"
@@ -15,14 +18,35 @@ import (
 
 // SyncService holds dependencies and implements record sync logic.
 type SyncService struct {
-	LocalDB    repository.DB
+	LocalDB     repository.DB
 	GetRemoteDB func(serverID int) repository.DB
-	Ut         utils.Utils
+	Ut          utils.Utils
+	Paths       PathLayout
+	Events      *EventBus
+
+	// Audit, when set, records every run of StartRecordProcessing with
+	// Actor (who started it) and AuditTarget (e.g. "/sync/runs/5").
+	Audit       Auditor
+	Actor       string
+	AuditTarget string
+
+	// run is swapped by the run goroutine and read by Report from others.
+	run   atomic.Pointer[runState]
+	trace *explainTrace
 }
 
 // NewSyncService creates a SyncService with the given dependencies.
+// Queries on the local and remote DBs are timed for the sync metrics.
 func NewSyncService(localDB repository.DB, getRemoteDB func(serverID int) repository.DB, ut utils.Utils) *SyncService {
-	return &SyncService{LocalDB: localDB, GetRemoteDB: getRemoteDB, Ut: ut}
+	return &SyncService{
+		LocalDB: instrumentDB(localDB, "local"),
+		GetRemoteDB: func(serverID int) repository.DB {
+			return instrumentDB(getRemoteDB(serverID), strconv.Itoa(serverID))
+		},
+		Ut:     ut,
+		Paths:  DefaultPathLayout(),
+		Events: NewEventBus(),
+	}
 }
 
 func (s *SyncService) getStreamTypeSQL(streamType string) string {
@@ -54,6 +78,12 @@ func parseServersOrder(serverOrderStr string) []int {
 	return order
 }
 
+// LocalServerID returns the server_number parameter of the local server.
+func (s *SyncService) LocalServerID() int {
+	id, _ := strconv.Atoi(s.Ut.GetParameter(s.LocalDB, "server_number"))
+	return id
+}
+
 // GetServersOrder returns streamID -> list of server IDs to try for import.
 func (s *SyncService) GetServersOrder(streamType string) map[int][]int {
 	serverOrderStr := s.Ut.GetParameter(s.LocalDB, fmt.Sprintf("server_order_%s_records_import", streamType))
@@ -68,7 +98,7 @@ func (s *SyncService) GetServersOrder(streamType string) map[int][]int {
 	)
 	streams, err := s.LocalDB.SelectStreams(sql)
 	if err != nil {
-		fmt.Println("DB error in getServersOrder:", err)
+		s.dbError("getServersOrder", err)
 		return serversOrder
 	}
 	for _, stream := range streams {
@@ -82,13 +112,6 @@ func (s *SyncService) GetServersOrder(streamType string) map[int][]int {
 	return serversOrder
 }
 
-func selectPathPrefix(svr, svrLocal int) string {
-	if svr == svrLocal {
-		return "/home/neurotime/stream_analyse/recording/"
-	}
-	return fmt.Sprintf("/mnt/fs_svr%d/recording/", svr)
-}
-
 func (s *SyncService) addRecordToImported(serverID, recordID int, imported map[int][]int) map[int][]int {
 	if imported == nil {
 		imported = make(map[int][]int)
@@ -184,7 +207,7 @@ order by stream_id, started_at
 `, syncStart1.Format("2006-01-02 15:04:05"), syncEnd.Format("2006-01-02 15:04:05"), streamID)
 	records, err := d.SelectRecords(sql)
 	if err != nil {
-		fmt.Println("DB error in getRecordingStatusInPeriodByStreamID:", err)
+		s.dbError("getRecordingStatusInPeriodByStreamID", err)
 		return nil, nil
 	}
 	var recorded []model.Period
@@ -203,7 +226,6 @@ order by stream_id, started_at
 				rec.Start = syncStart
 				break
 			}
-			i++
 		}
 	}
 	nonRecorded := extractNonRecordedPeriods(recorded, syncStart, syncEnd)
@@ -222,7 +244,7 @@ func (s *SyncService) getRecordingStatusInPeriod(d repository.DB, syncStart, syn
 	)
 	streams, err := d.SelectStreams(sql)
 	if err != nil {
-		fmt.Println("DB error in getRecordingStatusInPeriod:", err)
+		s.dbError("getRecordingStatusInPeriod", err)
 		return nil, nil
 	}
 	recordedPeriods := make(map[int][]model.Period)
@@ -281,7 +303,7 @@ where started_at between '%s' and '%s'
 	)
 	records, err := s.LocalDB.SelectRecords(sql)
 	if err != nil {
-		fmt.Println("DB error in isSimilarRecordExistsDB:", err)
+		s.dbError("isSimilarRecordExistsDB", err)
 		return false
 	}
 	return len(records) > 0
@@ -301,7 +323,7 @@ where started_at > '%s'
 	)
 	records, err := s.LocalDB.SelectRecords(sql)
 	if err != nil {
-		fmt.Println("DB error in getCoveredRecords:", err)
+		s.dbError("getCoveredRecords", err)
 		return nil
 	}
 	var ids []int
@@ -320,7 +342,7 @@ where imported_source_id = %d
 `, serverID, importedRecordID)
 	records, err := s.LocalDB.SelectRecords(sql)
 	if err != nil {
-		fmt.Println("DB error in isRecordInDB:", err)
+		s.dbError("isRecordInDB", err)
 		return false
 	}
 	return len(records) > 0
@@ -334,7 +356,19 @@ func (s *SyncService) CopyRecords(serverLocalID, disabledRecordID, serverID int,
 		disabledRecords = append(disabledRecords, disabledRecordID)
 	}
 	fmt.Println(imported)
-	isImported := isRecordInImported(serverID, record.ID, imported)
+	src, err := resolveRecordPath(s.Paths.Root(serverID, serverLocalID), record.Path)
+	if err != nil {
+		fmt.Println("  >> REJECTED PATH :", err)
+		s.traceStep("record %d from server %d rejected: %v", record.ID, serverID, err)
+		return "rejected_path", imported
+	}
+	dst, err := resolveRecordPath(s.Paths.Root(serverLocalID, serverLocalID), record.Path)
+	if err != nil {
+		fmt.Println("  >> REJECTED PATH :", err)
+		s.traceStep("record %d from server %d rejected: %v", record.ID, serverID, err)
+		return "rejected_path", imported
+	}
+	isImported := isImportedWithOrigin(serverID, record, imported)
 	var similarExists bool
 	if !isImported {
 		similarExists = s.isSimilarRecordExistsDB(record)
@@ -347,37 +381,43 @@ func (s *SyncService) CopyRecords(serverLocalID, disabledRecordID, serverID int,
 			reason = "similar record exists"
 		}
 		fmt.Println("  >> NOT NEED IMPORT :", reason)
+		s.traceStep("record %d from server %d not imported: %s", record.ID, serverID, reason)
 		status = "no_need"
 	} else {
-		src := selectPathPrefix(serverID, serverLocalID) + strings.ReplaceAll(record.Path, "./", "")
-		dst := selectPathPrefix(serverLocalID, serverLocalID) + strings.ReplaceAll(record.Path, "./", "")
+		base := strings.TrimSuffix(src, filepath.Ext(src))
+		srcPattern := base + "*"
 		fmt.Println("  >> copy", src)
 		if isSyncMode {
 			if s.isRecordInDB(record.ID, serverID) {
 				status = "no_need"
 				fmt.Println("  > record already imported")
+			} else if size := filesSize(srcPattern); s.isOutOfSpace(size, s.Paths.LocalRoot) {
+				status = "no_space"
+				fmt.Println("  > not enough disk space, copy skipped")
+				// Counted once in the bytes the remaining plan needs.
+				imported = s.markImported(serverID, record, imported)
 			} else {
-				base := strings.TrimSuffix(src, filepath.Ext(src))
-				srcPattern := base + "*"
 				dstDir := filepath.Dir(dst)
 				copyResult := s.Ut.CopyFilesToDir(srcPattern, dstDir, false, true)
 				if copyResult {
 					status = "updated"
 					fmt.Println("  > success copy to", dstDir)
+					syncBytesCopied.Add(float64(size), strconv.Itoa(serverID))
+					s.publish(Event{Type: EventItemCopied, StreamID: record.StreamID, RecordID: record.ID, ServerID: serverID, Path: dst})
 					startDBTime := time.Now()
 					disabledRecords = append(disabledRecords, s.getCoveredRecords(record)...)
 					if err := s.insertRecord(s.LocalDB, record, serverID); err != nil {
-						fmt.Println("  > insertRecord error:", err)
+						s.dbError("insertRecord", err)
 					}
 					fmt.Printf("    disabled_records_list = %v\n", disabledRecords)
 					if err := s.updateRecordNotApproved(s.LocalDB, disabledRecords); err != nil {
-						fmt.Println("  > update_record_not_approved error:", err)
+						s.dbError("updateRecordNotApproved", err)
 					}
 					if err := s.disableResults(s.LocalDB, disabledRecords); err != nil {
-						fmt.Println("  > disable_results error:", err)
+						s.dbError("disableResults", err)
 					}
 					fmt.Printf("  > db update duration : %v\n", time.Since(startDBTime))
-					imported = s.addRecordToImported(serverID, record.ID, imported)
+					imported = s.markImported(serverID, record, imported)
 				} else {
 					status = "no_success"
 					fmt.Println("  > error copy from", srcPattern)
@@ -385,24 +425,33 @@ func (s *SyncService) CopyRecords(serverLocalID, disabledRecordID, serverID int,
 			}
 		} else {
 			status = "updated"
-			imported = s.addRecordToImported(serverID, record.ID, imported)
+			s.traceStep("record %d from server %d would be copied from %s to %s", record.ID, serverID, src, dst)
+			imported = s.markImported(serverID, record, imported)
 		}
 	}
 	return status, imported
 }
 
-func (s *SyncService) getRecordsAccordingServersOrder(order []int, sql string) (int, []model.Record) {
+func (s *SyncService) getRecordsAccordingServersOrder(order []int, serverLocalID int, sql string) (int, []model.Record) {
 	for _, srv := range order {
 		d := s.GetRemoteDB(srv)
 		if d == nil {
+			s.traceServerError(srv, "no DB")
 			continue
 		}
 		recs, err := d.SelectRecords(sql)
 		if err != nil {
-			fmt.Println("DB error in getRecordsAccordingServersOrder:", err)
+			s.dbError("getRecordsAccordingServersOrder", err)
+			s.traceServerError(srv, err.Error())
 			continue
 		}
+		s.traceServer(srv, recs)
+		recs = dropLocalOrigin(recs, srv, serverLocalID)
 		if len(recs) > 0 {
+			s.traceStep("add mode: server %d is the first in import order with records (%d)", srv, len(recs))
+			for _, r := range recs {
+				s.traceSelected(srv, r.ID)
+			}
 			return srv, recs
 		}
 	}
@@ -414,27 +463,22 @@ func (s *SyncService) AddRecordsFromOtherServers(streamType string, serverLocalI
 	startedAt := s.Ut.BeginOfHour(record.StartedAt)
 	endedAt := s.Ut.EndOfHour(startedAt).Add(3 * time.Minute)
 	streamID := record.StreamID
-	sqlCondition := ""
-	if streamType == "video" {
-		sqlCondition = " and converted_to_low = true "
-	}
 	sql := fmt.Sprintf(`
 select * from records
-where ('%s' < started_at and ended_at < '%s')
-  and duration > 0
-  and stream_id = %d
-  and is_record_approved = true
-  and converted_to_mp3 = true
-  %s
+where %s
 order by started_at
-`, startedAt.Format("2006-01-02 15:04:05"), endedAt.Format("2006-01-02 15:04:05"), streamID, sqlCondition)
-	srv, recs := s.getRecordsAccordingServersOrder(serversOrder[streamID], sql)
+`, whereSQL(addModeFilters(streamID, streamType, startedAt, endedAt)))
+	s.traceStep("add mode query on servers %v:%s", serversOrder[streamID], sql)
+	srv, recs := s.getRecordsAccordingServersOrder(serversOrder[streamID], serverLocalID, sql)
 	status := ""
 	if len(recs) > 0 {
 		fmt.Printf("  > Start copy any records from server %d between '%s' and '%s' (add mode)\n",
 			srv, startedAt.Format("2006-01-02 15:04:05"), endedAt.Format("2006-01-02 15:04:05"))
 		for _, r := range recs {
 			status, imported = s.CopyRecords(serverLocalID, -1, srv, r, imported, isSyncMode)
+			if status == "updated" && isSyncMode {
+				syncGapSecondsFilled.Add(gapSecondsCovered(r, record.StartedAt, record.EndedAt), streamType)
+			}
 		}
 	} else {
 		fmt.Printf("  > Can't find any records from another servers between '%s' and '%s'\n",
@@ -444,6 +488,82 @@ order by started_at
 	return status, imported
 }
 
+// gapRecord is the placeholder record a non-recorded period is matched with.
+func gapRecord(p model.Period) model.Record {
+	return model.Record{
+		ID:             -1,
+		StartedAt:      p.Start,
+		EndedAt:        p.End,
+		Duration:       p.End.Sub(p.Start).Minutes(),
+		StreamID:       p.StreamID,
+		RecordRate:     0,
+		Path:           "",
+		StreamType:     0,
+		URLIndex:       0,
+		ConvertedToMP3: true,
+	}
+}
+
+// minGapSeconds is the shortest non-recorded period worth importing.
+const minGapSeconds = 20
+
+// matchDeltaSec is the start/end tolerance when matching a better record for a problem record.
+const matchDeltaSec = 10
+
+// matchThresholds returns the duration and record rate a candidate must exceed
+// to replace record in SyncRecordsFromOtherServers.
+func matchThresholds(record model.Record) (float64, float64) {
+	duration := record.Duration
+	if duration > 60 {
+		duration = 60
+	}
+	recordRate := record.RecordRate + 0.001
+	if recordRate > 0.999 {
+		recordRate = 0.999
+	}
+	return duration, recordRate
+}
+
+// problemRecordsSQL selects the approved local records that are too short or
+// have a low record rate, the first items of a sync run.
+func problemRecordsSQL(start, end time.Time, sqlStreamID, sqlStreamType string) string {
+	return fmt.Sprintf(`
+select * from records
+where started_at > '%s' and started_at < '%s'
+  %s
+  %s
+  and is_record_approved=true and (duration<61 or record_rate<1)
+order by started_at, stream_id
+`, start.Format("2006-01-02 15:04:05"), end.Format("2006-01-02 15:04:05"), sqlStreamID, sqlStreamType)
+}
+
+// loadImported returns the remote records already imported into the local DB
+// between start and end, keyed by source server, including their origins.
+func (s *SyncService) loadImported(start, end time.Time, sqlStreamID, sqlStreamType string, serverLocalID int) (map[int][]int, error) {
+	importedQuery := fmt.Sprintf(`
+select * from records
+where started_at > '%s' and started_at < '%s'
+  %s
+  %s
+  and (is_record_approved=true or is_record_checked=true)
+  and imported_record_id>0
+order by started_at, stream_id
+`, start.Format("2006-01-02 15:04:05"), end.Format("2006-01-02 15:04:05"), sqlStreamID, sqlStreamType)
+	importedRecords, err := s.LocalDB.SelectRecords(importedQuery)
+	if err != nil {
+		s.dbError("selecting imported records", err)
+		return nil, err
+	}
+	importedIDs := make(map[int][]int)
+	for _, r := range importedRecords {
+		importedIDs = s.addRecordToImported(r.ImportedSourceID, r.ImportedRecordID, importedIDs)
+		if originServerID, originRecordID := r.Origin(serverLocalID); originServerID != r.ImportedSourceID {
+			importedIDs = s.addRecordToImported(originServerID, originRecordID, importedIDs)
+		}
+	}
+	return importedIDs, nil
+}
+
 func getPeriodRate(r model.Record, periodStart, periodEnd time.Time) float64 {
 	dt1 := maxTime(r.StartedAt, periodStart)
 	dt2 := minTime(r.EndedAt, periodEnd)
@@ -481,20 +601,24 @@ func mathPow10(n int) float64 {
 	return p
 }
 
-func (s *SyncService) getRecordsFromServer(serverID int, sql string, startedAt, endedAt time.Time) *model.Record {
+func (s *SyncService) getRecordsFromServer(serverID, serverLocalID int, sql string, startedAt, endedAt time.Time) *model.Record {
 	fmt.Println("start select get_records_from_server")
 	startProcess := time.Now()
 	d := s.GetRemoteDB(serverID)
 	if d == nil {
 		fmt.Println("nil DB for server", serverID)
+		s.traceServerError(serverID, "no DB")
 		return nil
 	}
 	recs, err := d.SelectRecords(sql)
 	if err != nil {
-		fmt.Println("DB error in getRecordsFromServer:", err)
+		s.dbError("getRecordsFromServer", err)
+		s.traceServerError(serverID, err.Error())
 		return nil
 	}
 	fmt.Printf("end DB select with len = %d,    process duration : %v\n", len(recs), time.Since(startProcess))
+	s.traceServer(serverID, recs)
+	recs = dropLocalOrigin(recs, serverID, serverLocalID)
 	if len(recs) == 0 {
 		return nil
 	}
@@ -510,6 +634,7 @@ func (s *SyncService) getRecordsFromServer(serverID int, sql string, startedAt,
 		}
 	}
 	r := recs[maxIdx]
+	s.traceBest(serverID, r.ID)
 	fmt.Printf("  > db-%d, found records = %d   max rate id = %d\n", serverID, len(recs), maxIdx)
 	fmt.Println(" ", r.ID, " ", r.Path, " ", r.Duration, "min  ", r.RecordRate, " ",
 		r.StartedAt.Format("2006-01-02 15:04:05"), "  ", r.EndedAt.Format("2006-01-02 15:04:05"))
@@ -518,79 +643,28 @@ func (s *SyncService) getRecordsFromServer(serverID int, sql string, startedAt,
 
 // SyncRecordsFromOtherServers finds the best record from other servers and copies it.
 func (s *SyncService) SyncRecordsFromOtherServers(streamType string, serverLocalID int, record model.Record, imported map[int][]int, serversOrder map[int][]int, isNonRecordedPeriod, isSyncMode bool) (string, map[int][]int) {
-	const deltaSec = 10
 	recordID := record.ID
-	recordRate := record.RecordRate + 0.001
-	if recordRate > 0.999 {
-		recordRate = 0.999
-	}
 	startedAt := record.StartedAt
 	startedHour := s.Ut.BeginOfHour(startedAt)
 	endedAt := record.EndedAt
-	duration := record.Duration
-	if duration > 60 {
-		duration = 60
-	}
 	streamID := record.StreamID
-	start1 := startedAt.Add(-deltaSec * time.Second)
-	start2 := startedAt.Add(deltaSec * time.Second)
-	sqlCondition := ""
-	if streamType == "video" {
-		sqlCondition = " and converted_to_low = true "
-	}
-	var sql string
-	if isNonRecordedPeriod {
-		sql = fmt.Sprintf(`
-select * from records
-where started_at < '%s'
-  and ended_at > '%s'
-  and started_at >= '%s'
-  and stream_id = %d
-  and is_record_approved = true
-  and converted_to_mp3 = true
-  and is_deleted = false
-  and return_code=0
-  %s
-order by duration desc, started_at
-`, endedAt.Format("2006-01-02 15:04:05"), startedAt.Format("2006-01-02 15:04:05"),
-			startedHour.Format("2006-01-02 15:04:05"), streamID, sqlCondition)
-	} else {
-		sql = fmt.Sprintf(`
+	sql := fmt.Sprintf(`
 select * from records
-where
-  (
-    (
-      (
-        ((started_at - (interval '1 sec' * %d)) < '%s')
-        or (started_at between '%s' and '%s')
-      )
-      and '%s' < least(ended_at, started_at + (interval '1 min' * duration)) + (interval '1 sec' * %d)
-    )
-  )
-  and duration > %f
-  and record_rate > %f
-  and started_at >= '%s'
-  and stream_id = %d
-  and is_record_approved = true
-  and converted_to_mp3 = true
-  and is_deleted = false
-  and return_code=0
-  %s
+where %s
 order by duration desc, started_at
-`, deltaSec, startedAt.Format("2006-01-02 15:04:05"), start1.Format("2006-01-02 15:04:05"), start2.Format("2006-01-02 15:04:05"),
-			endedAt.Format("2006-01-02 15:04:05"), deltaSec, duration, recordRate,
-			startedHour.Format("2006-01-02 15:04:05"), streamID, sqlCondition)
-	}
+`, whereSQL(syncRecordFilters(record, streamType, isNonRecordedPeriod, startedHour)))
 	fmt.Println(sql)
+	s.traceStep("query on servers %v:%s", serversOrder[streamID], sql)
 	results := make(map[int]model.Record)
 	for _, serverID := range serversOrder[streamID] {
-		res := s.getRecordsFromServer(serverID, sql, startedAt, endedAt)
+		res := s.getRecordsFromServer(serverID, serverLocalID, sql, startedAt, endedAt)
 		if res != nil {
 			results[serverID] = *res
 		}
 	}
 	if len(results) == 0 {
 		fmt.Println("  > Can't find any records from another servers with more duration time")
+		s.traceStep("no server returned a candidate")
 		return "no_find", imported
 	}
 	maxRate := 0.0
@@ -601,6 +675,7 @@ order by duration desc, started_at
 		fmt.Printf("       %d  compare_rate=%f  %s  %f  %s  %s\n",
 			r.ID, compareRate, r.Path, r.RecordRate,
 			r.StartedAt.Format("2006-01-02 15:04:05"), r.EndedAt.Format("2006-01-02 15:04:05"))
+		s.traceCompare(sid, r.ID, compareRate)
 		if compareRate > maxRate {
 			maxRate = compareRate
 			maxServerID = sid
@@ -608,16 +683,36 @@ order by duration desc, started_at
 	}
 	if maxRate <= 0 {
 		fmt.Println("  > Can't find records from any server with record_rate>0")
+		s.traceStep("no candidate has compare_rate > 0")
 		return "no_find", imported
 	}
 	fmt.Println("  >> get max result from db", maxServerID)
 	best := results[maxServerID]
+	s.traceSelected(maxServerID, best.ID)
+	s.traceStep("selected record %d from server %d with the highest compare_rate %.2f", best.ID, maxServerID, maxRate)
 	status, imported2 := s.CopyRecords(serverLocalID, recordID, maxServerID, best, imported, isSyncMode)
+	if isNonRecordedPeriod && status == "updated" && isSyncMode {
+		syncGapSecondsFilled.Add(gapSecondsCovered(best, startedAt, endedAt), streamType)
+	}
 	return status, imported2
 }
 
 // StartRecordProcessing runs the full sync: problem records first, then non-recorded periods.
-func (s *SyncService) StartRecordProcessing(args Args, periodType string) {
+// It returns an error when the run could not start or finished with failed copies or DB errors.
+func (s *SyncService) StartRecordProcessing(args Args, periodType string) error {
+	return s.StartRecordProcessingContext(context.Background(), args, periodType)
+}
+
+// StartRecordProcessingContext is StartRecordProcessing with cancellation.
+// A cancelled run stops between items, so no copy is left half-recorded in the DB.
+func (s *SyncService) StartRecordProcessingContext(ctx context.Context, args Args, periodType string) (runErr error) {
+	defer func() {
+		if p := recover(); p != nil {
+			s.auditRun(args, periodType, fmt.Errorf("panic: %v", p))
+			panic(p)
+		}
+		s.auditRun(args, periodType, runErr)
+	}()
 	startProcessing := time.Now()
 	fmt.Println("\nSTARTED at", startProcessing)
 	isSyncMode := args.Sync
@@ -625,28 +720,40 @@ func (s *SyncService) StartRecordProcessing(args Args, periodType string) {
 	streamType := args.StreamType
 	isAddMode := args.AddMode
 	isNoTask := args.NoTask
+	s.run.Store(newRunState(args.MinFreeBytes))
 
-	serverLocalIDStr := s.Ut.GetParameter(s.LocalDB, "server_number")
-	serverLocalID, _ := strconv.Atoi(serverLocalIDStr)
+	serverLocalID := s.LocalServerID()
 
 	serversOrder := s.GetServersOrder(streamType)
 	fmt.Println("servers order :", serversOrder)
 	fmt.Println()
 	if len(serversOrder) == 0 {
 		fmt.Println("Not defined servers order")
-		return
+		return errors.New("servers order not defined")
 	}
 
 	if streamType == "video" {
 		if s.Ut.GetParameter(s.LocalDB, "is_video_processing") != "1" &&
 			s.Ut.GetParameter(s.LocalDB, "is_band_processing") != "1" {
 			fmt.Printf("Current server is not process %s files and can't import %s records\n", streamType, streamType)
-			return
+			return fmt.Errorf("server does not process %s files", streamType)
 		}
 	} else {
 		if s.Ut.GetParameter(s.LocalDB, fmt.Sprintf("is_%s_processing", streamType)) != "1" {
 			fmt.Printf("Current server is not process %s files and can't import %s records\n", streamType, streamType)
-			return
+			return fmt.Errorf("server does not process %s files", streamType)
+		}
+	}
+
+	if args.Incremental && isSyncMode && !s.canSetWatermark() {
+		fmt.Println("Incremental mode needs utils that implement SetParameter to store the watermark")
+		return errors.New("incremental mode: utils do not implement SetParameter")
+	}
+
+	if isSyncMode && s.run.Load().minFreeBytes > 0 {
+		if !s.checkFreeSpace(s.Paths.LocalRoot, 0) {
+			fmt.Println("Not enough free disk space to import records")
+			return fmt.Errorf("not enough free disk space on %s", s.Paths.LocalRoot)
 		}
 	}
 
@@ -654,8 +761,8 @@ func (s *SyncService) StartRecordProcessing(args Args, periodType string) {
 	if isSyncMode && !isNoTask {
 		taskID = s.Ut.CreateTask(s.LocalDB, "records_sync", true)
 		if taskID < 0 {
-			fmt.Println("\n Another records sync process is running \n")
-			return
+			fmt.Println("\n Another records sync process is running")
+			return errors.New("another records sync process is running")
 		}
 	} else {
 		taskID = -1
@@ -679,11 +786,25 @@ func (s *SyncService) StartRecordProcessing(args Args, periodType string) {
 			syncTimeStart = s.Ut.BeginOfHour(dt1)
 			syncTimeEnd = s.Ut.EndOfHour(dt2)
 		}
+		if args.Incremental {
+			if wm, ok := s.Watermark(streamType); ok {
+				fmt.Println("watermark         =", wm)
+				syncTimeStart = wm.Add(-args.Overlap)
+			} else {
+				fmt.Println("No watermark yet for", streamType, "- using the auto window")
+			}
+		}
 	default:
 		syncTimeStart = now
 		syncTimeEnd = now
 	}
 
+	s.run.Load().update(func(r *RunReport) {
+		r.LocalServerID = serverLocalID
+		r.TaskID = taskID
+		r.SyncStart = syncTimeStart
+		r.SyncEnd = syncTimeEnd
+	})
 	fmt.Println("local_server      =", serverLocalID)
 	fmt.Println("task_id           =", taskID)
 	fmt.Println("start sync time   =", syncTimeStart)
@@ -691,47 +812,36 @@ func (s *SyncService) StartRecordProcessing(args Args, periodType string) {
 	fmt.Println("stream_id         =", streamID)
 	fmt.Println("stream_type       =", streamType)
 	fmt.Println("sync_mode         =", isSyncMode)
-	time.Sleep(5 * time.Second)
+	fmt.Println("min free bytes    =", args.MinFreeBytes)
+	if !syncTimeStart.Before(syncTimeEnd) {
+		fmt.Println("Nothing to sync: start sync time is not before end sync time")
+		s.updateProgress(taskID, 100)
+		return nil
+	}
+	select {
+	case <-time.After(5 * time.Second):
+	case <-ctx.Done():
+		fmt.Println("Sync cancelled before start:", ctx.Err())
+		return ctx.Err()
+	}
 
 	sqlStreamID := ""
 	if streamID >= 0 {
 		sqlStreamID = fmt.Sprintf(" and stream_id=%d ", streamID)
 	}
 	sqlStreamType := s.getStreamTypeSQL(streamType)
-	sqlQuery := fmt.Sprintf(`
-select * from records
-where started_at > '%s' and started_at < '%s'
-  %s
-  %s
-  and is_record_approved=true and (duration<61 or record_rate<1)
-order by started_at, stream_id
-`, syncTimeStart.Format("2006-01-02 15:04:05"), syncTimeEnd.Format("2006-01-02 15:04:05"), sqlStreamID, sqlStreamType)
+	sqlQuery := problemRecordsSQL(syncTimeStart, syncTimeEnd, sqlStreamID, sqlStreamType)
 	fmt.Println(sqlQuery)
 
 	records1, err := s.LocalDB.SelectRecords(sqlQuery)
 	if err != nil {
-		fmt.Println("DB error selecting problem records:", err)
-		return
+		s.dbError("selecting problem records", err)
+		return err
 	}
 
-	importedQuery := fmt.Sprintf(`
-select * from records
-where started_at > '%s' and started_at < '%s'
-  %s
-  %s
-  and (is_record_approved=true or is_record_checked=true)
-  and imported_record_id>0
-order by started_at, stream_id
-`, syncTimeStart.Format("2006-01-02 15:04:05"), syncTimeEnd.Format("2006-01-02 15:04:05"), sqlStreamID, sqlStreamType)
-	importedRecords, err := s.LocalDB.SelectRecords(importedQuery)
+	importedIDs, err := s.loadImported(syncTimeStart, syncTimeEnd, sqlStreamID, sqlStreamType, serverLocalID)
 	if err != nil {
-		fmt.Println("DB error selecting imported records:", err)
-		return
-	}
-
-	importedIDs := make(map[int][]int)
-	for _, r := range importedRecords {
-		importedIDs = s.addRecordToImported(r.ImportedSourceID, r.ImportedRecordID, importedIDs)
+		return err
 	}
 	fmt.Println("Already imported:", importedIDs)
 
@@ -741,16 +851,20 @@ order by started_at, stream_id
 	fmt.Printf("\nStart processing %d records\n", len(records1))
 
 	n := 0
-	nUpdated := 0
-	nNoNeed := 0
-	nNoFind := 0
-	nNoSuccess := 0
 	nn := len(records1)
-	s.Ut.UpdateCompletionPercentage(s.LocalDB, taskID, 1)
+	s.updateProgress(taskID, 1)
 
+	// Once out of space nothing more is copied, but the remaining items are
+	// still planned: every copy they would make is refused as no_space and
+	// its size added to the bytes the remaining plan needs.
 	for _, r := range records1 {
+		if ctx.Err() != nil {
+			break
+		}
 		n++
 		fmt.Println("\n\n", "process records :", n, "of", nn)
+		s.publish(Event{Type: EventItemStarted, Item: n, Total: nn, StreamID: r.StreamID, RecordID: r.ID,
+			Start: timePtr(r.StartedAt), End: timePtr(r.EndedAt), Path: r.Path})
 		fmt.Println(" ", r.ID, " ", r.Path, " ", r.Duration, "min  ", r.RecordRate, " ",
 			r.StartedAt.Format("2006-01-02 15:04:05"), "  ", r.EndedAt.Format("2006-01-02 15:04:05"))
 		status := ""
@@ -760,20 +874,16 @@ order by started_at, stream_id
 			fmt.Println("  >> NO need process : record already disabled by previous import")
 			status = "no_need"
 		}
-		switch status {
-		case "updated":
-			nUpdated++
-		case "no_need":
-			nNoNeed++
-		case "no_find":
-			nNoFind++
-		case "no_success":
-			nNoSuccess++
-		}
-		s.Ut.UpdateCompletionPercentage(s.LocalDB, taskID, 50*float64(n)/float64(nn))
+		s.countStatus(status, n, nn)
+		syncRecordsProcessed.Inc(status, streamType)
+		s.publish(Event{Type: EventItemDecided, Item: n, Total: nn, StreamID: r.StreamID, RecordID: r.ID, Status: status})
+		s.updateProgress(taskID, 50*float64(n)/float64(nn))
 	}
 
-	_, nonRecorded := s.getRecordingStatusInPeriod(s.LocalDB, syncTimeStart, syncTimeEnd, streamType, streamID)
+	var nonRecorded map[int][]model.Period
+	if ctx.Err() == nil {
+		_, nonRecorded = s.getRecordingStatusInPeriod(s.LocalDB, syncTimeStart, syncTimeEnd, streamType, streamID)
+	}
 	fmt.Println("\n\n------------------------------------------------------------------------------------------------------------------------------------")
 	fmt.Println("Start processing for non-recorded periods at", time.Now())
 	fmt.Println("------------------------------------------------------------------------------------------------------------------------------------")
@@ -782,48 +892,38 @@ order by started_at, stream_id
 	nn = nn + len(sortedNonRecorded)
 
 	for _, p := range sortedNonRecorded {
+		if ctx.Err() != nil {
+			break
+		}
 		n++
 		fmt.Println("\n\n", "process non_recorded_periods :", n, " of", nn)
+		s.publish(Event{Type: EventItemStarted, Item: n, Total: nn, StreamID: p.StreamID,
+			Start: timePtr(p.Start), End: timePtr(p.End)})
 		startProcessTime := time.Now()
+		syncGapSecondsFound.Add(p.End.Sub(p.Start).Seconds(), streamType)
 		fmt.Println(" stream_id =", p.StreamID, " ", s.Ut.GetStreamNameByID(s.LocalDB, p.StreamID), " ",
 			p.Start.Format("2006-01-02 15:04:05"), "  ", p.End.Format("2006-01-02 15:04:05"), "  duration =", p.End.Sub(p.Start))
 		status := ""
-		if p.End.Sub(p.Start).Seconds() < 20 {
+		if p.End.Sub(p.Start).Seconds() < minGapSeconds {
 			status = "no_need"
 			fmt.Println("  >> NOT NEED IMPORT")
 		} else {
-			r := model.Record{
-				ID:             -1,
-				StartedAt:      p.Start,
-				EndedAt:        p.End,
-				Duration:       p.End.Sub(p.Start).Minutes(),
-				StreamID:       p.StreamID,
-				RecordRate:     0,
-				Path:           "",
-				StreamType:     0,
-				URLIndex:       0,
-				ConvertedToMP3: true,
-			}
+			r := gapRecord(p)
 			status, importedIDs = s.SyncRecordsFromOtherServers(streamType, serverLocalID, r, importedIDs, serversOrder, true, isSyncMode)
 			if status == "no_find" && isAddMode {
 				status, importedIDs = s.AddRecordsFromOtherServers(streamType, serverLocalID, r, importedIDs, serversOrder, isSyncMode)
 			}
 		}
-		switch status {
-		case "updated":
-			nUpdated++
-		case "no_need":
-			nNoNeed++
-		case "no_find":
-			nNoFind++
-		case "no_success":
-			nNoSuccess++
-		}
-		s.Ut.UpdateCompletionPercentage(s.LocalDB, taskID, 100*float64(n)/float64(nn))
+		s.countStatus(status, n, nn)
+		syncRecordsProcessed.Inc(status, streamType)
+		s.publish(Event{Type: EventItemDecided, Item: n, Total: nn, StreamID: p.StreamID, Status: status})
+		s.updateProgress(taskID, 100*float64(n)/float64(nn))
 		fmt.Printf(" process duration : %v\n", time.Since(startProcessTime))
 	}
 
-	s.Ut.UpdateCompletionPercentage(s.LocalDB, taskID, 100)
+	if ctx.Err() == nil && !s.outOfSpace() {
+		s.updateProgress(taskID, 100)
+	}
 	fmt.Println("\n\nDone!")
 	fmt.Println("=============================================================")
 	fmt.Println("local_server      =", serverLocalID)
@@ -835,25 +935,58 @@ order by started_at, stream_id
 	fmt.Println("sync_mode         =", isSyncMode)
 	fmt.Println()
 	fmt.Println("Total records with problems  =", n)
-	fmt.Println("Updated records              =", nUpdated)
-	fmt.Println("No need update records       =", nNoNeed)
-	fmt.Println("Can't find records           =", nNoFind)
-	fmt.Println("No success sync              =", nNoSuccess)
+	report := s.Report()
+	fmt.Println("Updated records              =", report.Statuses["updated"])
+	fmt.Println("No need update records       =", report.Statuses["no_need"])
+	fmt.Println("Can't find records           =", report.Statuses["no_find"])
+	fmt.Println("No success sync              =", report.Statuses["no_success"])
+	fmt.Println("No disk space                =", report.Statuses["no_space"])
+	fmt.Println("Rejected record paths        =", report.Statuses["rejected_path"])
+	if report.OutOfSpace {
+		fmt.Println()
+		fmt.Println("STOPPED: not enough free disk space on", s.Paths.LocalRoot)
+		fmt.Println("Bytes needed for remaining plan =", report.BytesNeeded)
+	}
+	if ctx.Err() != nil {
+		fmt.Println()
+		fmt.Println("CANCELLED:", ctx.Err())
+	}
 	fmt.Println("=============================================================")
 	fmt.Println("STARTED   at", startProcessing)
 	fmt.Println("FINISHED  at", time.Now())
 	fmt.Println("DURATION  =", time.Since(startProcessing))
+
+	switch {
+	case ctx.Err() != nil:
+		runErr = fmt.Errorf("cancelled after %d items: %w", n, ctx.Err())
+	case report.OutOfSpace:
+		runErr = fmt.Errorf("stopped: not enough free disk space, remaining plan needs %d bytes", report.BytesNeeded)
+	case report.DBErrors > 0 || report.Statuses["no_success"] > 0:
+		runErr = fmt.Errorf("finished with %d DB errors and %d failed copies", report.DBErrors, report.Statuses["no_success"])
+	}
+	if args.Incremental && isSyncMode && streamID < 0 {
+		if runErr == nil {
+			s.setWatermark(streamType, syncTimeEnd)
+			fmt.Println("Watermark advanced to", syncTimeEnd.Format(watermarkLayout))
+		} else {
+			fmt.Println("Watermark not advanced:", runErr)
+		}
+	}
+	return runErr
 }
 
 // Args holds CLI arguments for the sync command.
 type Args struct {
-	StartDatetime time.Time
-	EndDatetime   time.Time
-	AutoDays      *int
-	AutoHours     *int
-	StreamType    string
-	StreamID      int
-	Sync          bool
-	AddMode       bool
-	NoTask        bool
+	StartDatetime time.Time     `json:"start,omitempty"`
+	EndDatetime   time.Time     `json:"end,omitempty"`
+	AutoDays      *int          `json:"days,omitempty"`
+	AutoHours     *int          `json:"hours,omitempty"`
+	StreamType    string        `json:"stream_type"`
+	StreamID      int           `json:"stream_id"`
+	Sync          bool          `json:"sync"`
+	AddMode       bool          `json:"add_mode"`
+	NoTask        bool          `json:"no_task"`
+	MinFreeBytes  int64         `json:"min_free_bytes"`
+	Incremental   bool          `json:"incremental"`
+	Overlap       time.Duration `json:"overlap_ns"`
 }
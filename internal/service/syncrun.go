@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Sync run states.
+const (
+	RunRunning   = "running"
+	RunFinished  = "finished"
+	RunFailed    = "failed"
+	RunCancelled = "cancelled"
+)
+
+// maxFinishedRuns bounds how many finished runs a SyncRunner remembers.
+const maxFinishedRuns = 200
+
+var (
+	ErrRunNotFound   = errors.New("sync run not found")
+	ErrRunNotRunning = errors.New("sync run is not running")
+	ErrRunnerClosed  = errors.New("sync runner is shutting down")
+	ErrRunActive     = errors.New("a sync run is already running")
+)
+
+// Validate checks args the same way sync-cli does for the given period type.
+func (a Args) Validate(periodType string) error {
+	if a.StreamType != "audio" && a.StreamType != "video" {
+		return errors.New("stream_type must be audio or video")
+	}
+	switch periodType {
+	case "period":
+		if a.StartDatetime.IsZero() || a.EndDatetime.IsZero() {
+			return errors.New("start and end are required")
+		}
+		if !a.StartDatetime.Before(a.EndDatetime) {
+			return errors.New("start must be before end")
+		}
+		if a.Incremental {
+			return errors.New("incremental is only available in auto mode")
+		}
+	case "auto":
+		days := a.AutoDays != nil && *a.AutoDays > 0
+		hours := a.AutoHours != nil && *a.AutoHours > 0
+		if days == hours {
+			return errors.New("set either days or hours (only one)")
+		}
+		if a.Incremental && a.StreamID >= 0 {
+			return errors.New("incremental mode syncs all streams of a type; stream_id is not allowed")
+		}
+	default:
+		return fmt.Errorf("unknown period type %q", periodType)
+	}
+	if a.Overlap < 0 {
+		return errors.New("overlap must not be negative")
+	}
+	if a.MinFreeBytes < 0 {
+		return errors.New("min free space must not be negative")
+	}
+	return nil
+}
+
+// SyncRun describes a sync run started by a SyncRunner.
+type SyncRun struct {
+	ID         int64      `json:"id"`
+	PeriodType string     `json:"period_type"`
+	Args       Args       `json:"args"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Report     RunReport  `json:"report"`
+}
+
+type runEntry struct {
+	run    SyncRun
+	svc    *SyncService
+	cancel context.CancelFunc
+}
+
+// SyncRunner runs SyncService jobs in background goroutines and keeps their
+// state in memory. Every run gets its own SyncService from newService.
+type SyncRunner struct {
+	newService func() *SyncService
+
+	mu      sync.Mutex
+	nextID  int64
+	runs    map[int64]*runEntry
+	closed  bool
+	running sync.WaitGroup
+}
+
+// NewSyncRunner creates a SyncRunner.
+func NewSyncRunner(newService func() *SyncService) *SyncRunner {
+	return &SyncRunner{newService: newService, runs: make(map[int64]*runEntry)}
+}
+
+// Start validates args and starts a run in the background on behalf of actor,
+// who is recorded with the run's audit entry. Only one run at a time: runs
+// with no_task take no task lock that would keep a second one out.
+func (r *SyncRunner) Start(args Args, periodType, actor string) (SyncRun, error) {
+	if err := args.Validate(periodType); err != nil {
+		return SyncRun{}, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &runEntry{svc: r.newService(), cancel: cancel}
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		cancel()
+		return SyncRun{}, ErrRunnerClosed
+	}
+	for _, other := range r.runs {
+		if other.run.Status == RunRunning {
+			r.mu.Unlock()
+			cancel()
+			return SyncRun{}, fmt.Errorf("%w: run %d", ErrRunActive, other.run.ID)
+		}
+	}
+	r.nextID++
+	e.run = SyncRun{
+		ID:         r.nextID,
+		PeriodType: periodType,
+		Args:       args,
+		Status:     RunRunning,
+		StartedAt:  time.Now(),
+	}
+	r.runs[e.run.ID] = e
+	e.svc.Actor = actor
+	e.svc.AuditTarget = fmt.Sprintf("/sync/runs/%d", e.run.ID)
+	r.pruneLocked()
+	run := e.snapshotLocked()
+	r.running.Add(1)
+	r.mu.Unlock()
+
+	go r.execute(ctx, e)
+	return run, nil
+}
+
+func (r *SyncRunner) execute(ctx context.Context, e *runEntry) {
+	defer r.running.Done()
+	err := func() (err error) {
+		// A panicking run must not take the whole server down.
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("panic: %v", p)
+			}
+		}()
+		return e.svc.StartRecordProcessingContext(ctx, e.run.Args, e.run.PeriodType)
+	}()
+	e.cancel()
+
+	r.mu.Lock()
+	now := time.Now()
+	e.run.FinishedAt = &now
+	switch {
+	case err == nil:
+		e.run.Status = RunFinished
+	case errors.Is(err, context.Canceled):
+		e.run.Status = RunCancelled
+		e.run.Error = err.Error()
+	default:
+		e.run.Status = RunFailed
+		e.run.Error = err.Error()
+	}
+	finished := Event{Type: EventRunFinished, Status: e.run.Status, Error: e.run.Error}
+	r.mu.Unlock()
+
+	if e.svc.Events != nil {
+		e.svc.Events.Publish(finished)
+		e.svc.Events.Close()
+	}
+}
+
+// snapshotLocked returns a copy of the run with the current report. r.mu must be held.
+func (e *runEntry) snapshotLocked() SyncRun {
+	run := e.run
+	run.Report = e.svc.Report()
+	return run
+}
+
+// pruneLocked drops the oldest finished runs beyond maxFinishedRuns. r.mu must be held.
+func (r *SyncRunner) pruneLocked() {
+	var finished []int64
+	for id, e := range r.runs {
+		if e.run.Status != RunRunning {
+			finished = append(finished, id)
+		}
+	}
+	if len(finished) <= maxFinishedRuns {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i] < finished[j] })
+	for _, id := range finished[:len(finished)-maxFinishedRuns] {
+		delete(r.runs, id)
+	}
+}
+
+// List returns all known runs, newest first.
+func (r *SyncRunner) List() []SyncRun {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	runs := make([]SyncRun, 0, len(r.runs))
+	for _, e := range r.runs {
+		runs = append(runs, e.snapshotLocked())
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ID > runs[j].ID })
+	return runs
+}
+
+// Get returns the run with the given ID and its current report.
+func (r *SyncRunner) Get(id int64) (SyncRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.runs[id]
+	if !ok {
+		return SyncRun{}, ErrRunNotFound
+	}
+	return e.snapshotLocked(), nil
+}
+
+// Cancel asks a running run to stop after its current item.
+func (r *SyncRunner) Cancel(id int64) (SyncRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.runs[id]
+	if !ok {
+		return SyncRun{}, ErrRunNotFound
+	}
+	if e.run.Status != RunRunning {
+		return e.snapshotLocked(), ErrRunNotRunning
+	}
+	e.cancel()
+	return e.snapshotLocked(), nil
+}
+
+// Events returns the progress event bus of a run. The bus is closed once the run has finished.
+func (r *SyncRunner) Events(id int64) (*EventBus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.runs[id]
+	if !ok {
+		return nil, ErrRunNotFound
+	}
+	if e.svc.Events == nil {
+		return nil, errors.New("sync run has no event bus")
+	}
+	return e.svc.Events, nil
+}
+
+// Shutdown refuses new runs, asks every running run to stop after its current
+// item and waits until they have returned or ctx is done.
+func (r *SyncRunner) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	r.closed = true
+	for _, e := range r.runs {
+		if e.run.Status == RunRunning {
+			e.cancel()
+		}
+	}
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("sync runs still running: %w", ctx.Err())
+	}
+}
@@ -1,10 +1,23 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
 	"myproject/internal/model"
 	"myproject/internal/repository"
 )
 
+var (
+	// ErrUserNotFound is returned when no user has the requested id.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrEmailTaken is returned when another user already has the email.
+	ErrEmailTaken = errors.New("email already in use")
+)
+
 // UserService contains business logic for users.
 type UserService struct {
 	repo *repository.UserRepo
@@ -15,7 +28,155 @@ func NewUserService(repo *repository.UserRepo) *UserService {
 	return &UserService{repo: repo}
 }
 
-// List returns all users (business rules applied here).
-func (s *UserService) List() ([]*model.User, error) {
-	return s.repo.List()
+// User list page sizes.
+const (
+	DefaultUserPageSize = 50
+	MaxUserPageSize     = 200
+)
+
+// ListUsersParams are the query parameters of GET /users.
+type ListUsersParams struct {
+	// Limit is the page size; 0 means DefaultUserPageSize.
+	Limit int
+	// Cursor is the NextCursor of the previous page.
+	Cursor string
+	Active *bool
+	Email  string
+	Query  string
+	// Sort is "id" (default) or "-id".
+	Sort string
+}
+
+// UserPage is one page of users; NextCursor is empty on the last page.
+type UserPage struct {
+	Users      []*model.User
+	NextCursor string
+}
+
+// List returns one page of users matching p (business rules applied here).
+func (s *UserService) List(p ListUsersParams) (*UserPage, error) {
+	var v validator
+	f := model.UserFilter{
+		Active: p.Active,
+		Email:  strings.TrimSpace(p.Email),
+		Query:  strings.TrimSpace(p.Query),
+		Limit:  p.Limit,
+	}
+	if f.Limit == 0 {
+		f.Limit = DefaultUserPageSize
+	}
+	if f.Limit < 1 || f.Limit > MaxUserPageSize {
+		v.add("limit", fmt.Sprintf("must be between 1 and %d", MaxUserPageSize))
+	}
+	switch p.Sort {
+	case "", "id":
+		p.Sort = "id"
+	case "-id":
+		f.Desc = true
+	default:
+		v.add("sort", "must be id or -id")
+	}
+	if p.Cursor != "" {
+		id, ok := decodeCursor(p.Sort, p.Cursor)
+		if !ok {
+			v.add("cursor", "is invalid or was made for another sort order")
+		}
+		f.AfterID = id
+	}
+	if utf8.RuneCountInString(f.Query) > maxTextLen {
+		v.add("q", fmt.Sprintf("must be at most %d characters", maxTextLen))
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	// One extra row tells whether there is a next page.
+	limit := f.Limit
+	f.Limit++
+	users, err := s.repo.List(f)
+	if err != nil {
+		return nil, err
+	}
+	page := &UserPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = encodeCursor(p.Sort, page.Users[limit-1].ID)
+	}
+	return page, nil
+}
+
+// Ping checks the users DB (readiness).
+func (s *UserService) Ping(ctx context.Context) error {
+	return s.repo.Ping(ctx)
+}
+
+// Get returns user id or ErrUserNotFound.
+func (s *UserService) Get(id int64) (*model.User, error) {
+	u, err := s.repo.Get(id)
+	return u, userError(err)
+}
+
+// Create creates a user; name and email are required, active defaults to true.
+// Invalid input gives a *ValidationError with every failing field.
+func (s *UserService) Create(in model.CreateUserInput) (*model.User, error) {
+	u := &model.User{
+		Name:   strings.TrimSpace(in.Name),
+		Email:  strings.TrimSpace(in.Email),
+		Active: true,
+	}
+	if in.Active != nil {
+		u.Active = *in.Active
+	}
+	var v validator
+	v.text("name", u.Name, true)
+	v.text("email", u.Email, true)
+	v.email("email", u.Email)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	u, err := s.repo.Create(u)
+	return u, userError(err)
+}
+
+// Update changes the fields of user id set in in and returns the updated user.
+// Only the given fields are validated; at least one is needed.
+func (s *UserService) Update(id int64, in model.UpdateUserInput) (*model.User, error) {
+	var v validator
+	if in.Name == nil && in.Email == nil && in.Active == nil {
+		v.add("", "at least one of name, email or active is required")
+	}
+	if in.Name != nil {
+		name := strings.TrimSpace(*in.Name)
+		v.text("name", name, false)
+		in.Name = &name
+	}
+	if in.Email != nil {
+		email := strings.TrimSpace(*in.Email)
+		v.text("email", email, false)
+		v.email("email", email)
+		in.Email = &email
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	u, err := s.repo.Update(id, in)
+	return u, userError(err)
+}
+
+// Delete deletes user id or returns ErrUserNotFound.
+func (s *UserService) Delete(id int64) error {
+	return userError(s.repo.Delete(id))
+}
+
+// userError maps repository errors to the user service errors.
+func userError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrUserNotFound
+	case errors.Is(err, repository.ErrDuplicate):
+		// email is the only unique column of users besides id.
+		return ErrEmailTaken
+	default:
+		return err
+	}
 }
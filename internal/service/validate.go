@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrValidation is matched by every *ValidationError.
+var ErrValidation = errors.New("validation failed")
+
+// maxTextLen is the length of the VARCHAR(255) text columns.
+const maxTextLen = 255
+
+// FieldError is a validation problem with one input field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every field error of an input.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		if f.Field == "" {
+			parts[i] = f.Message
+		} else {
+			parts[i] = f.Field + ": " + f.Message
+		}
+	}
+	return fmt.Sprintf("%s: %s", ErrValidation, strings.Join(parts, "; "))
+}
+
+// Unwrap lets errors.Is(err, ErrValidation) match.
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+// validator collects field errors; err returns nil when there are none.
+type validator struct {
+	fields []FieldError
+}
+
+func (v *validator) add(field, message string) {
+	v.fields = append(v.fields, FieldError{Field: field, Message: message})
+}
+
+// text checks a trimmed text value against the column rules.
+func (v *validator) text(field, value string, required bool) {
+	switch {
+	case value == "":
+		if required {
+			v.add(field, "is required")
+		} else {
+			v.add(field, "must not be empty")
+		}
+	case utf8.RuneCountInString(value) > maxTextLen:
+		v.add(field, fmt.Sprintf("must be at most %d characters", maxTextLen))
+	}
+}
+
+// email checks value is a bare address like "ann@example.com".
+func (v *validator) email(field, value string) {
+	if value == "" || utf8.RuneCountInString(value) > maxTextLen {
+		return
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value || !strings.Contains(value[strings.LastIndex(value, "@")+1:], ".") {
+		v.add(field, "must be a valid email address")
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: v.fields}
+}
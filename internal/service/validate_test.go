@@ -0,0 +1,85 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	long := strings.Repeat("é", maxTextLen+1)
+	tests := []struct {
+		name  string
+		check func(v *validator)
+		want  []FieldError
+	}{
+		{"valid text and email", func(v *validator) {
+			v.text("name", "Ann", true)
+			v.text("email", "ann@example.com", true)
+			v.email("email", "ann@example.com")
+		}, nil},
+		{"required text missing", func(v *validator) { v.text("name", "", true) },
+			[]FieldError{{"name", "is required"}}},
+		{"optional text empty", func(v *validator) { v.text("name", "", false) },
+			[]FieldError{{"name", "must not be empty"}}},
+		{"text at the limit", func(v *validator) { v.text("name", long[:len(long)-len("é")], true) }, nil},
+		{"text too long in characters", func(v *validator) { v.text("name", long, true) },
+			[]FieldError{{"name", fmt.Sprintf("must be at most %d characters", maxTextLen)}}},
+		{"email with display name", func(v *validator) { v.email("email", "Ann <ann@example.com>") },
+			[]FieldError{{"email", "must be a valid email address"}}},
+		{"email without domain dot", func(v *validator) { v.email("email", "ann@localhost") },
+			[]FieldError{{"email", "must be a valid email address"}}},
+		{"email without at", func(v *validator) { v.email("email", "ann.example.com") },
+			[]FieldError{{"email", "must be a valid email address"}}},
+		{"empty email is left to text", func(v *validator) { v.email("email", "") }, nil},
+		{"every field error is kept in order", func(v *validator) {
+			v.add("", "at least one field is required")
+			v.text("name", "", true)
+			v.text("email", "x", true)
+			v.email("email", "x")
+		}, []FieldError{
+			{"", "at least one field is required"},
+			{"name", "is required"},
+			{"email", "must be a valid email address"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v validator
+			tt.check(&v)
+			err := v.err()
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("err = %v, want nil", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("err = %v, want *ValidationError", err)
+			}
+			if !reflect.DeepEqual(ve.Fields, tt.want) {
+				t.Errorf("fields %+v, want %+v", ve.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrorMapping(t *testing.T) {
+	err := error(&ValidationError{Fields: []FieldError{
+		{Field: "", Message: "at least one of name, email or active is required"},
+		{Field: "email", Message: "must be a valid email address"},
+	}})
+	want := "validation failed: at least one of name, email or active is required; email: must be a valid email address"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	if wrapped := fmt.Errorf("create user: %w", err); !errors.Is(wrapped, ErrValidation) {
+		t.Error("wrapped ValidationError does not match ErrValidation")
+	}
+	if errors.Is(errors.New("validation failed"), ErrValidation) {
+		t.Error("an unrelated error matches ErrValidation")
+	}
+}
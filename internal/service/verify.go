@@ -0,0 +1,262 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"myproject/internal/model"
+)
+
+// Problems found by Verify.
+const (
+	VerifyMissing      = "missing"
+	VerifyEmpty        = "empty"
+	VerifyCorrupt      = "corrupt"
+	VerifyRejectedPath = "rejected_path"
+)
+
+// VerifyProblem is an approved record whose local file is missing or damaged.
+type VerifyProblem struct {
+	RecordID         int       `json:"record_id"`
+	StreamID         int       `json:"stream_id"`
+	Start            time.Time `json:"start"`
+	End              time.Time `json:"end"`
+	Path             string    `json:"path"`
+	File             string    `json:"file,omitempty"`
+	Problem          string    `json:"problem"`
+	Detail           string    `json:"detail,omitempty"`
+	ImportedSourceID int       `json:"imported_source_id,omitempty"`
+	ImportedRecordID int       `json:"imported_record_id,omitempty"`
+	// SHA256 and SourceSHA256 are the checksums of the local file and of the
+	// copy on the source server when --rehash found them different.
+	SHA256       string `json:"sha256,omitempty"`
+	SourceFile   string `json:"source_file,omitempty"`
+	SourceSHA256 string `json:"source_sha256,omitempty"`
+	// Action is what was done about the problem: disabled, reimported or a failure.
+	Action string `json:"action,omitempty"`
+}
+
+// VerifyReport is the outcome of Verify.
+type VerifyReport struct {
+	Start    time.Time       `json:"start"`
+	End      time.Time       `json:"end"`
+	Checked  int             `json:"checked"`
+	Counts   map[string]int  `json:"counts"`
+	Problems []VerifyProblem `json:"problems"`
+}
+
+// Verify checks that every approved local record that started between start
+// and end has a non-empty file under the local recording root. With rehash
+// every file is read in full; for imported records its SHA-256 is compared
+// with the file on the source server when that one is mounted.
+func (s *SyncService) Verify(start, end time.Time, streamType string, streamID int, rehash bool) (*VerifyReport, error) {
+	if s.LocalDB == nil {
+		return nil, fmt.Errorf("no local DB")
+	}
+	sqlStreamID := ""
+	if streamID >= 0 {
+		sqlStreamID = fmt.Sprintf(" and stream_id=%d ", streamID)
+	}
+	sql := fmt.Sprintf(`
+select * from records
+where started_at >= '%s' and started_at < '%s'
+  %s
+  %s
+  and is_record_approved = true
+  and is_deleted = false
+order by started_at, stream_id
+`, start.Format("2006-01-02 15:04:05"), end.Format("2006-01-02 15:04:05"), sqlStreamID, s.getStreamTypeSQL(streamType))
+	records, err := s.LocalDB.SelectRecords(sql)
+	if err != nil {
+		return nil, fmt.Errorf("select records: %w", err)
+	}
+
+	serverLocalID := s.LocalServerID()
+	report := &VerifyReport{Start: start, End: end, Counts: make(map[string]int)}
+	for _, r := range records {
+		report.Checked++
+		p := s.verifyRecord(r, serverLocalID, rehash)
+		if p == nil {
+			continue
+		}
+		report.Counts[p.Problem]++
+		report.Problems = append(report.Problems, *p)
+	}
+	return report, nil
+}
+
+func (s *SyncService) verifyRecord(r model.Record, serverLocalID int, rehash bool) *VerifyProblem {
+	p := &VerifyProblem{
+		RecordID:         r.ID,
+		StreamID:         r.StreamID,
+		Start:            r.StartedAt,
+		End:              r.EndedAt,
+		Path:             r.Path,
+		ImportedSourceID: r.ImportedSourceID,
+		ImportedRecordID: r.ImportedRecordID,
+	}
+	file, err := resolveRecordPath(s.Paths.LocalRoot, r.Path)
+	if err != nil {
+		p.Problem, p.Detail = VerifyRejectedPath, err.Error()
+		return p
+	}
+	p.File = file
+	fi, err := os.Stat(file)
+	switch {
+	case os.IsNotExist(err):
+		p.Problem = VerifyMissing
+		return p
+	case err != nil:
+		p.Problem, p.Detail = VerifyCorrupt, err.Error()
+		return p
+	case !fi.Mode().IsRegular():
+		p.Problem, p.Detail = VerifyCorrupt, "not a regular file"
+		return p
+	case fi.Size() == 0:
+		p.Problem = VerifyEmpty
+		return p
+	}
+	if !rehash {
+		return nil
+	}
+	sum, err := fileSHA256(file)
+	if err != nil {
+		p.Problem, p.Detail = VerifyCorrupt, err.Error()
+		return p
+	}
+	if r.ImportedSourceID <= 0 || r.ImportedSourceID == serverLocalID {
+		return nil
+	}
+	src, err := resolveRecordPath(s.Paths.Root(r.ImportedSourceID, serverLocalID), r.Path)
+	if err != nil {
+		return nil
+	}
+	srcFi, err := os.Stat(src)
+	if err != nil {
+		// The source copy may be gone or not mounted; the local file read fine.
+		return nil
+	}
+	srcSum, err := fileSHA256(src)
+	if err != nil {
+		return nil
+	}
+	if srcSum != sum {
+		p.Problem = VerifyCorrupt
+		p.SHA256, p.SourceFile, p.SourceSHA256 = sum, src, srcSum
+		p.Detail = fmt.Sprintf("sha256 %s (%d bytes) differs from server %d: %s (%d bytes)%s",
+			sum[:12], fi.Size(), r.ImportedSourceID, srcSum[:12], srcFi.Size(), shorterSide(fi.Size(), srcFi.Size()))
+		return p
+	}
+	return nil
+}
+
+// shorterSide names the copy that is likely truncated when the sizes differ;
+// with equal sizes the checksums alone can't tell which side is damaged.
+func shorterSide(local, source int64) string {
+	switch {
+	case local < source:
+		return ", local file is shorter"
+	case source < local:
+		return ", source file is shorter"
+	}
+	return ", same size"
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyAsideSuffix is appended to a damaged file while it is reimported.
+const verifyAsideSuffix = ".verify-old"
+
+// DisableVerified marks the records of problems not approved and disables
+// their results, so their periods become gaps for the next sync run.
+func (s *SyncService) DisableVerified(problems []VerifyProblem) error {
+	if s.LocalDB == nil {
+		return fmt.Errorf("no local DB")
+	}
+	ids := make([]int, 0, len(problems))
+	for _, p := range problems {
+		ids = append(ids, p.RecordID)
+	}
+	if err := s.updateRecordNotApproved(s.LocalDB, ids); err != nil {
+		return fmt.Errorf("update records: %w", err)
+	}
+	if err := s.disableResults(s.LocalDB, ids); err != nil {
+		return fmt.Errorf("disable results: %w", err)
+	}
+	for i := range problems {
+		problems[i].Action = "disabled"
+	}
+	return nil
+}
+
+// ReimportVerified disables the records of problems and copies the imported
+// ones again from their source server. Records recorded on the local server
+// stay disabled and are filled by the next sync run. minFreeBytes is the free
+// space guard of the copies, as in a sync run. A damaged local file is moved
+// aside during the copy and only deleted once the copy is updated; otherwise it
+// is put back.
+func (s *SyncService) ReimportVerified(problems []VerifyProblem, minFreeBytes int64) error {
+	if err := s.DisableVerified(problems); err != nil {
+		return err
+	}
+	s.run.Store(newRunState(minFreeBytes))
+	serverLocalID := s.LocalServerID()
+	for i := range problems {
+		p := &problems[i]
+		if p.ImportedSourceID <= 0 || p.ImportedRecordID <= 0 || p.ImportedSourceID == serverLocalID {
+			p.Action = "disabled, left for the next sync run"
+			continue
+		}
+		d := s.GetRemoteDB(p.ImportedSourceID)
+		if d == nil {
+			p.Action = fmt.Sprintf("disabled, no DB for server %d", p.ImportedSourceID)
+			continue
+		}
+		recs, err := d.SelectRecords(fmt.Sprintf("select * from records where id = %d", p.ImportedRecordID))
+		if err != nil || len(recs) == 0 {
+			p.Action = fmt.Sprintf("disabled, record %d not found on server %d", p.ImportedRecordID, p.ImportedSourceID)
+			continue
+		}
+		// A damaged file would block the copy, which never overwrites.
+		aside := ""
+		if p.File != "" && p.Problem != VerifyMissing {
+			aside = p.File + verifyAsideSuffix
+			if err := os.Rename(p.File, aside); err != nil {
+				p.Action = "disabled, can't move damaged file aside: " + err.Error()
+				continue
+			}
+		}
+		status, _ := s.CopyRecords(serverLocalID, -1, p.ImportedSourceID, recs[0], nil, true)
+		p.Action = "reimport: " + status
+		if aside == "" {
+			continue
+		}
+		if status == "updated" {
+			if err := os.Remove(aside); err != nil {
+				p.Action += ", can't remove damaged file: " + err.Error()
+			}
+			continue
+		}
+		if err := os.Rename(aside, p.File); err != nil {
+			p.Action += fmt.Sprintf(", damaged file left at %s: %v", aside, err)
+		} else {
+			p.Action += ", damaged file restored"
+		}
+	}
+	return nil
+}
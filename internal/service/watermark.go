@@ -0,0 +1,41 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"myproject/internal/utils"
+)
+
+const watermarkLayout = "2006-01-02 15:04:05"
+
+func watermarkParameter(streamType string) string {
+	return fmt.Sprintf("records_sync_watermark_%s", streamType)
+}
+
+// Watermark returns the end of the last sync run for streamType that finished
+// without errors. ok is false when no incremental run has completed yet.
+func (s *SyncService) Watermark(streamType string) (time.Time, bool) {
+	v := s.Ut.GetParameter(s.LocalDB, watermarkParameter(streamType))
+	if v == "" {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(watermarkLayout, v, time.Local)
+	if err != nil {
+		fmt.Println("Invalid watermark", v, "for", streamType, ":", err)
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// canSetWatermark reports whether s.Ut can store the watermark (utils.ParameterSetter).
+func (s *SyncService) canSetWatermark() bool {
+	_, ok := s.Ut.(utils.ParameterSetter)
+	return ok
+}
+
+func (s *SyncService) setWatermark(streamType string, t time.Time) {
+	if ps, ok := s.Ut.(utils.ParameterSetter); ok {
+		ps.SetParameter(s.LocalDB, watermarkParameter(streamType), t.Format(watermarkLayout))
+	}
+}
@@ -18,3 +18,9 @@ type Utils interface {
 	CreateTask(db interface{}, taskType string, checkRunning bool) int
 	GetStreamNameByID(db interface{}, streamID int) string
 }
+
+// ParameterSetter is implemented by Utils that can also store parameters.
+// It is optional: only incremental sync runs need it, to store their watermark.
+type ParameterSetter interface {
+	SetParameter(db interface{}, key, value string)
+}
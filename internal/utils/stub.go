@@ -0,0 +1,27 @@
+package utils
+
+import "time"
+
+// Stub is a no-op implementation of Utils for when DB/Utils are not yet wired.
+type Stub struct{}
+
+func (Stub) GetParameter(db interface{}, key string) string { return "" }
+func (Stub) SetParameter(db interface{}, key, value string) {}
+func (Stub) CopyFilesToDir(srcPattern, dstDir string, overwrite, printLog bool) bool {
+	return false
+}
+func (Stub) BeginOfHour(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+func (Stub) EndOfHour(t time.Time) time.Time {
+	return Stub{}.BeginOfHour(t).Add(time.Hour).Add(-time.Nanosecond)
+}
+func (Stub) BeginOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+func (Stub) EndOfDay(t time.Time) time.Time {
+	return Stub{}.BeginOfDay(t).Add(24 * time.Hour).Add(-time.Nanosecond)
+}
+func (Stub) UpdateCompletionPercentage(db interface{}, taskID int, percent float64) {}
+func (Stub) CreateTask(db interface{}, taskType string, checkRunning bool) int      { return -1 }
+func (Stub) GetStreamNameByID(db interface{}, streamID int) string                  { return "" }
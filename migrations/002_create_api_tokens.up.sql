@@ -0,0 +1,15 @@
+-- API tokens for myapp. Only the SHA-256 of a token is stored; the token
+-- itself is printed once by `myapp token issue`.
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id         BIGSERIAL PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    role       VARCHAR(16) NOT NULL CHECK (role IN ('viewer', 'operator', 'admin')),
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
+
+COMMENT ON TABLE api_tokens IS 'Bearer tokens of the myapp API; revoked tokens are kept for reference.';
+COMMENT ON COLUMN api_tokens.token_hash IS 'Hex SHA-256 of the token.';
+COMMENT ON COLUMN api_tokens.role IS 'viewer: read; operator: read and sync control; admin: everything incl. user management.';
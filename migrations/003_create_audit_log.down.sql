@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_audit_log_target;
+DROP INDEX IF EXISTS idx_audit_log_action;
+DROP INDEX IF EXISTS idx_audit_log_actor;
+DROP INDEX IF EXISTS idx_audit_log_created_at;
+DROP TABLE IF EXISTS audit_log;
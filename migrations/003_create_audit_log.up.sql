@@ -0,0 +1,25 @@
+-- Audit log of API mutations and sync runs: who did what to which target,
+-- with which parameters and how it ended.
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id             BIGSERIAL PRIMARY KEY,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    actor          VARCHAR(255) NOT NULL,
+    actor_token_id BIGINT,
+    action         VARCHAR(64) NOT NULL,
+    target         VARCHAR(255) NOT NULL,
+    params         JSONB NOT NULL DEFAULT '{}',
+    outcome        VARCHAR(16) NOT NULL CHECK (outcome IN ('success', 'failure', 'cancelled')),
+    error          TEXT NOT NULL DEFAULT '',
+    request_id     VARCHAR(128) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log (action, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log (target, id);
+
+COMMENT ON TABLE audit_log IS 'Append-only; written by myapp handlers and by every sync run (API, sync-cli, daemon).';
+COMMENT ON COLUMN audit_log.actor IS 'API token name, cli:<os user> or daemon:<job>.';
+COMMENT ON COLUMN audit_log.actor_token_id IS 'api_tokens.id of the actor for API requests.';
+COMMENT ON COLUMN audit_log.target IS 'Resource path, e.g. /users/12 or /sync/runs/5.';
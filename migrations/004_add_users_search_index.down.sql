@@ -0,0 +1,3 @@
+-- pg_trgm is left installed; other objects may use it.
+DROP INDEX IF EXISTS idx_users_email_trgm;
+DROP INDEX IF EXISTS idx_users_name_trgm;
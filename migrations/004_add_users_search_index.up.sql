@@ -0,0 +1,7 @@
+-- Trigram indexes for GET /users?q=, which matches name and email with
+-- ILIKE '%q%'; the btree idx_users_email can't serve a substring match.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_users_name_trgm ON users USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING gin (email gin_trgm_ops);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_records_origin;
+ALTER TABLE records DROP COLUMN IF EXISTS origin_record_id;
+ALTER TABLE records DROP COLUMN IF EXISTS origin_server_id;
@@ -0,0 +1,17 @@
+-- Track where each record was first recorded so imports keep provenance
+-- across servers (A -> B -> C keeps A's server and record ID).
+
+ALTER TABLE records ADD COLUMN IF NOT EXISTS origin_server_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE records ADD COLUMN IF NOT EXISTS origin_record_id INTEGER NOT NULL DEFAULT 0;
+
+-- Rows imported before provenance was tracked: best known origin is the import source.
+UPDATE records
+SET origin_server_id = imported_source_id,
+    origin_record_id = imported_record_id
+WHERE imported_record_id > 0
+  AND origin_server_id = 0;
+
+CREATE INDEX IF NOT EXISTS idx_records_origin ON records (origin_server_id, origin_record_id);
+
+COMMENT ON COLUMN records.origin_server_id IS 'Server where the record was first recorded; 0 for local recordings.';
+COMMENT ON COLUMN records.origin_record_id IS 'Record ID on origin_server_id.';